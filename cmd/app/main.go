@@ -3,27 +3,37 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/riandyrn/otelchi"
 
 	"github.com/justcgh9/vk-internship-application/pkg/tracing"
 
 	"github.com/justcgh9/vk-internship-application/internal/config"
+	adminhandler "github.com/justcgh9/vk-internship-application/internal/http/handlers/admin"
 	authhandler "github.com/justcgh9/vk-internship-application/internal/http/handlers/auth"
+	"github.com/justcgh9/vk-internship-application/internal/http/handlers/health"
 	listingshandler "github.com/justcgh9/vk-internship-application/internal/http/handlers/listings"
+	apimiddleware "github.com/justcgh9/vk-internship-application/internal/http/middleware"
+	marketplacemetrics "github.com/justcgh9/vk-internship-application/internal/metrics"
+	"github.com/justcgh9/vk-internship-application/internal/migrator"
 	"github.com/justcgh9/vk-internship-application/internal/service/auth"
 	"github.com/justcgh9/vk-internship-application/internal/service/listing"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+	"github.com/justcgh9/vk-internship-application/internal/storage/memory"
 	"github.com/justcgh9/vk-internship-application/internal/storage/postgres"
+	"github.com/justcgh9/vk-internship-application/pkg/httpclient"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
 	"github.com/justcgh9/vk-internship-application/pkg/logger"
 	"github.com/justcgh9/vk-internship-application/pkg/metrics"
 )
@@ -32,25 +42,83 @@ func main() {
 
 	cfg := config.MustLoad()
 
-	logger.Init(slog.LevelDebug)
+	closeLog, err := setupLogging(cfg.Logging)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up logging: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeLog()
 	logger.Log.Info("Starting application...")
 
-	metrics.Init()
+	appMetrics := metrics.New(metrics.Options{
+		DurationBuckets: cfg.Metrics.DurationBuckets,
+		SizeBuckets:     cfg.Metrics.SizeBuckets,
+	})
+	marketplaceMetrics := marketplacemetrics.New(appMetrics.Registry())
+
+	var (
+		store       repositories
+		revocations storage.RevocationStore
+		checks      []health.Check
+	)
+	switch cfg.Storage {
+	case "memory":
+		logger.Log.Warn("Using in-memory storage, data will be lost on restart")
+		memStore := memory.NewStorage()
+		store, revocations = memStore, memory.NewRevocationStore(memStore)
+	default:
+		poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURI)
+		if err != nil {
+			logger.Log.Error("Invalid DB connection string", slog.Any("err", err))
+			os.Exit(1)
+		}
+		poolConfig.ConnConfig.Tracer = postgres.NewQueryTracer(cfg.SlowQueryThreshold)
+
+		dbpool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+		if err != nil {
+			logger.Log.Error("Failed to connect to DB", slog.Any("err", err))
+			os.Exit(1)
+		}
+		defer dbpool.Close()
+		appMetrics.Registry().MustRegister(metrics.NewPoolCollector(dbpool))
+
+		latest, err := prepareSchema(context.Background(), dbpool, cfg)
+		if err != nil {
+			logger.Log.Error("Database schema is not ready", slog.Any("err", err))
+			os.Exit(1)
+		}
+		checks = append(checks,
+			health.Check{Name: "postgres", Fn: dbpool.Ping},
+			health.Check{Name: "migrations", Fn: func(ctx context.Context) error {
+				return migrator.CheckVersion(ctx, dbpool, latest)
+			}},
+		)
+
+		pgStore := postgres.NewStorage(dbpool)
+		store, revocations = pgStore, pgStore
+		if cfg.RevocationStore == "memory" {
+			revocations = memory.NewRevocationStore(pgStore)
+		}
+	}
 
-	dbpool, err := pgxpool.New(context.Background(), cfg.DatabaseURI)
+	tokenManager, err := newTokenManager(cfg)
 	if err != nil {
-		logger.Log.Error("Failed to connect to DB", slog.Any("err", err))
+		logger.Log.Error("Failed to load JWT keys", slog.Any("err", err))
 		os.Exit(1)
 	}
-	defer dbpool.Close()
-
-	store := postgres.NewStorage(dbpool)
 
-	authSvc := auth.New(store, auth.NewTokenManager(cfg.JWTSecret, cfg.TokenTTL))
-	listingSvc := listing.New(store)
+	authSvc := auth.New(store, store, store, revocations, tokenManager, cfg.RefreshTokenTTL, marketplaceMetrics)
+	listingSvc := listing.New(store, marketplaceMetrics)
 
 	ctx := context.Background()
-	shutdownTracing, err := tracing.Init(ctx, "vk-intern-app")
+	shutdownTracing, err := tracing.Init(ctx, tracing.Options{
+		ServiceName: cfg.Tracing.ServiceName,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		TLS:         cfg.Tracing.TLS,
+		CAFile:      cfg.Tracing.CAFile,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
 	if err != nil {
 		logger.Log.Error("failed to initialize tracing", slog.Any("err", err))
 		return
@@ -60,17 +128,28 @@ func main() {
 			logger.Log.Error("error shutting down tracer", slog.Any("err", err))
 		}
 	}()
+	checks = append(checks, health.Check{Name: "tracing", Fn: tracing.Check, Optional: true})
 
 	r := chi.NewRouter()
-	r.Use(middleware.RequestLogger(&middleware.DefaultLogFormatter{Logger: slog.NewLogLogger(logger.Log.Handler(), slog.LevelDebug)}))
+	r.Use(otelchi.Middleware(cfg.Tracing.ServiceName))
+	r.Use(apimiddleware.RequestLogger)
+	r.Use(appMetrics.Middleware)
 	r.Use(middleware.Recoverer)
-	r.Use(metrics.Middleware)
-	r.Use(otelchi.Middleware("vk-intern-app"))
 
-	r.Get("/metrics", func(w http.ResponseWriter, r *http.Request) {
-		promhttp.Handler().ServeHTTP(w, r)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		httpx.WriteProblem(w, r, httpx.ErrNotFound)
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		httpx.WriteProblem(w, r, httpx.ErrMethodNotAllowed)
 	})
 
+	healthHandler := health.New(cfg.Server.HealthCheckTimeout, checks...)
+	r.Get("/healthz", healthHandler.Live)
+	r.Get("/readyz", healthHandler.Ready)
+	r.Get("/startupz", healthHandler.Started)
+
+	r.Method(http.MethodGet, "/metrics", appMetrics.Handler())
+
 	validate := validator.New()
 
 	authHandler := authhandler.New(
@@ -79,15 +158,33 @@ func main() {
 	)
 
 	r.Mount("/auth", authHandler.Routes(authSvc))
+	r.Get("/.well-known/jwks.json", authHandler.JWKS)
 
 	listingsHandler := listingshandler.New(
 		authSvc,
 		listingSvc,
 		validate,
+		marketplaceMetrics,
+		httpclient.New(httpclient.Options{
+			Timeout:          cfg.HTTPClient.Timeout,
+			HostTimeouts:     cfg.HTTPClient.HostTimeouts,
+			MaxRetries:       cfg.HTTPClient.MaxRetries,
+			RetryBackoff:     cfg.HTTPClient.RetryBackoff,
+			BreakerThreshold: cfg.HTTPClient.BreakerThreshold,
+			BreakerCooldown:  cfg.HTTPClient.BreakerCooldown,
+		}),
 	)
 
 	r.Mount("/listings", listingsHandler.Routes(authSvc))
 
+	adminHandler := adminhandler.New(
+		authSvc,
+		validate,
+		logger.Level,
+	)
+
+	r.Mount("/admin", adminHandler.Routes(authSvc))
+
 	addr := fmt.Sprintf(":%s", cfg.Server.Port)
 	srv := &http.Server{
 		Addr:         addr,
@@ -110,6 +207,9 @@ func main() {
 	<-stop
 
 	logger.Log.Info("Shutting down server...")
+	healthHandler.Drain()
+	time.Sleep(cfg.Server.DrainDelay)
+
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.Timeout)
 	defer cancel()
 
@@ -117,3 +217,109 @@ func main() {
 		logger.Log.Error("Failed graceful shutdown", slog.Any("err", err))
 	}
 }
+
+type repositories interface {
+	storage.UserRepository
+	storage.ListingRepository
+	storage.RefreshTokenRepository
+	storage.UnitOfWork
+}
+
+// setupLogging configures logger.Log. The returned function closes the log
+// file, if there is one.
+func setupLogging(cfg config.Logging) (func(), error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, err
+	}
+
+	out, closeOut := io.Writer(os.Stdout), func() {}
+	switch cfg.Output {
+	case "", "stdout":
+	case "stderr":
+		out = os.Stderr
+	default:
+		f, err := os.OpenFile(cfg.Output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		out, closeOut = f, func() { _ = f.Close() }
+	}
+
+	err := logger.Setup(logger.Options{
+		Level:  level,
+		Format: cfg.Format,
+		Output: out,
+		Redact: cfg.Redact,
+		Sampling: logger.Sampling{
+			Initial:    cfg.Sampling.Initial,
+			Thereafter: cfg.Sampling.Thereafter,
+		},
+	})
+	if err != nil {
+		closeOut()
+		return nil, err
+	}
+	return closeOut, nil
+}
+
+// prepareSchema applies the pending migrations when auto_migrate is on and
+// otherwise makes sure there are none. It returns the version the binary
+// expects.
+func prepareSchema(ctx context.Context, dbpool *pgxpool.Pool, cfg *config.Config) (uint, error) {
+	src, err := migrator.Source("")
+	if err != nil {
+		return 0, err
+	}
+	m, err := migrator.New(src, cfg.DatabaseURI)
+	if err != nil {
+		return 0, err
+	}
+	defer m.Close()
+
+	if !cfg.AutoMigrate {
+		return m.Latest(), m.Check()
+	}
+
+	conn, err := dbpool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	return m.Latest(), migrator.WithLock(ctx, conn, func() error {
+		before, err := m.Version()
+		if err != nil {
+			return err
+		}
+		if err := m.Up(); err != nil {
+			return err
+		}
+		after, err := m.Version()
+		if err != nil {
+			return err
+		}
+		if before != after {
+			logger.Log.Info("Applied migrations", slog.String("from", before.String()), slog.String("to", after.String()))
+		}
+		return nil
+	})
+}
+
+// newTokenManager falls back to the shared jwt_secret when no key set is configured.
+func newTokenManager(cfg *config.Config) (*auth.TokenManager, error) {
+	if len(cfg.JWT.Keys) == 0 {
+		return auth.NewTokenManager(cfg.JWTSecret, cfg.TokenTTL), nil
+	}
+
+	keys := make([]*auth.Key, 0, len(cfg.JWT.Keys))
+	for _, k := range cfg.JWT.Keys {
+		key, err := auth.LoadKey(k.ID, k.Algorithm, k.PrivateKeyPath, k.PublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return auth.NewKeyedTokenManager(cfg.JWT.SigningKeyID, keys, cfg.TokenTTL)
+}
@@ -1,49 +1,213 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/justcgh9/vk-internship-application/internal/migrator"
 )
 
-func main() {
+const usage = `Usage: migrator [flags] <command> [args]
+
+Commands:
+  up            apply every pending migration
+  down          revert every migration
+  goto N        migrate up or down to version N
+  steps ±N      apply N migrations, or revert them when N is negative
+  force N       set the version to N without running anything (-1 for none)
+  status        list the migrations and whether they are applied
+  version       print the current version
+  create NAME   add an empty migration to -dir
 
-	dbURL := flag.String("db", "", "PostgreSQL connection string")
-	migrationsPath := flag.String("path", "", "Path to migration files")
-	action := flag.String("action", "up", "Migration action: up or down")
+Flags:
+`
+
+func main() {
+	dbURL := flag.String("db", os.Getenv("DATABASE_URL"), "PostgreSQL connection string, DATABASE_URL by default")
+	migrationsPath := flag.String("path", "", "Read migrations from this directory instead of the embedded ones")
+	dryRun := flag.Bool("dry-run", false, "Print the SQL that would run and exit")
+	yes := flag.Bool("yes", false, "Do not ask for confirmation before reverting migrations on a remote database")
+	dir := flag.String("dir", "migrations", "Directory create writes new migrations to")
 
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	if *dbURL == "" || *migrationsPath == "" {
-		log.Fatal("Database connection string and migration path are required")
+	cmd, args := flag.Arg(0), flag.Args()[min(1, flag.NArg()):]
+	if cmd == "" {
+		flag.Usage()
+		os.Exit(2)
 	}
 
-	m, err := migrate.New(
-		fmt.Sprintf("file://%s", *migrationsPath),
-		*dbURL,
-	)
+	if cmd == "create" {
+		if len(args) != 1 {
+			log.Fatal("Usage: migrator create NAME")
+		}
+		up, down, err := migrator.Create(*dir, args[0])
+		if err != nil {
+			log.Fatalf("Failed to create migration: %v", err)
+		}
+		fmt.Println(up)
+		fmt.Println(down)
+		return
+	}
+
+	if *dbURL == "" {
+		log.Fatal("Database connection string is required")
+	}
+
+	src, err := migrator.Source(*migrationsPath)
+	if err != nil {
+		log.Fatalf("Failed to read migrations: %v", err)
+	}
+	m, err := migrator.New(src, *dbURL)
 	if err != nil {
 		log.Fatalf("Failed to initialize migration: %v", err)
 	}
+	defer m.Close()
 
-	switch *action {
-	case "up":
-		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-			log.Fatalf("Migration up failed: %v", err)
+	var target migrator.Version
+	switch cmd {
+	case "status":
+		printStatus(m)
+		return
+
+	case "version":
+		v, err := m.Version()
+		if err != nil {
+			log.Fatalf("Failed to read version: %v", err)
 		}
-		log.Println("Migrations applied successfully.")
+		fmt.Println(v)
+		return
 
+	case "force":
+		n := intArg(cmd, args)
+		if *dryRun {
+			fmt.Printf("-- would set the version to %d\n", n)
+			return
+		}
+		if err := m.Force(n); err != nil {
+			log.Fatalf("Force failed: %v", err)
+		}
+		log.Printf("Version set to %d.", n)
+		return
+
+	case "up":
+		target = migrator.Version{Version: m.Latest(), None: m.Latest() == 0}
 	case "down":
-		if err := m.Down(); err != nil && err != migrate.ErrNoChange {
-			log.Fatalf("Migration down failed: %v", err)
+		target = migrator.Version{None: true}
+	case "goto":
+		n := intArg(cmd, args)
+		if n < 0 {
+			log.Fatalf("Invalid version: %d", n)
+		}
+		target = migrator.Version{Version: uint(n)}
+	case "steps":
+		if target, err = m.StepsTarget(intArg(cmd, args)); err != nil {
+			log.Fatalf("Steps failed: %v", err)
 		}
-		log.Println("Migrations reverted successfully.")
 
 	default:
-		log.Fatalf("Invalid action: %s. Use 'up' or 'down'", *action)
+		log.Fatalf("Unknown command: %s. Run migrator -h for the list", cmd)
+	}
+
+	plan, err := m.Plan(target)
+	if err != nil {
+		log.Fatalf("Failed to plan migration: %v", err)
+	}
+	if len(plan) == 0 {
+		log.Println("No change.")
+		return
+	}
+
+	if *dryRun {
+		for _, step := range plan {
+			fmt.Printf("-- %04d_%s.%s.sql\n%s\n", step.Version, step.Name, step.Direction, step.SQL)
+		}
+		return
+	}
+
+	if reverts(plan) && !*yes && !migrator.IsLocal(*dbURL) {
+		confirm(*dbURL, plan)
+	}
+
+	if err := m.Migrate(target); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+	log.Printf("Migrated to version %s.", target)
+}
+
+func intArg(cmd string, args []string) int {
+	if len(args) != 1 {
+		log.Fatalf("Usage: migrator %s N", cmd)
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		log.Fatalf("Invalid number: %s", args[0])
+	}
+	return n
+}
+
+func printStatus(m *migrator.Migrator) {
+	status, err := m.Status()
+	if err != nil {
+		log.Fatalf("Failed to read status: %v", err)
+	}
+	v, err := m.Version()
+	if err != nil {
+		log.Fatalf("Failed to read version: %v", err)
+	}
+
+	for _, mig := range status {
+		state := "pending"
+		if mig.Applied {
+			state = "applied"
+		}
+		fmt.Printf("%04d  %-8s %s\n", mig.Version, state, mig.Name)
+	}
+	fmt.Printf("\nCurrent version: %s, latest: %d\n", v, m.Latest())
+}
+
+func reverts(plan []migrator.Step) bool {
+	for _, step := range plan {
+		if step.Direction == migrator.Down {
+			return true
+		}
+	}
+	return false
+}
+
+// confirm asks to type the database name before reverting migrations on a
+// database that is not on this machine, and exits unless it matches. A URL
+// without a database name cannot be confirmed this way and needs -yes.
+func confirm(dbURL string, plan []migrator.Step) {
+	var name string
+	if u, err := url.Parse(dbURL); err == nil {
+		name = strings.TrimPrefix(u.Path, "/")
+	}
+	if name == "" {
+		log.Fatal("The database URL names no database to confirm; pass -yes to revert migrations anyway")
+	}
+
+	fmt.Fprintf(os.Stderr, "About to revert %d migration(s) on a remote database:\n", len(plan))
+	for _, step := range plan {
+		if step.Direction == migrator.Down {
+			fmt.Fprintf(os.Stderr, "  %04d_%s\n", step.Version, step.Name)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Type the database name (%s) to continue: ", name)
+
+	// Without a full line, e.g. when stdin is closed, the answer is no.
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil || strings.TrimSpace(answer) != name {
+		log.Fatal("Aborted.")
 	}
 }
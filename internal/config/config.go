@@ -12,11 +12,94 @@ type Config struct {
 		Port        string        `yaml:"port"`
 		Timeout     time.Duration `yaml:"timeout"`
 		IdleTimeout time.Duration `yaml:"idle_timeout"`
+
+		// DrainDelay is how long /readyz fails before the server stops
+		// accepting connections, for load balancers to notice.
+		DrainDelay         time.Duration `yaml:"drain_delay" env-default:"5s"`
+		HealthCheckTimeout time.Duration `yaml:"health_check_timeout" env-default:"2s"`
 	} `yaml:"server"`
 
-	DatabaseURI string        `yaml:"db_uri"`
-	JWTSecret   string        `yaml:"jwt_secret"`
-	TokenTTL    time.Duration `yaml:"token_ttl" env-default:"15m"`
+	Logging Logging `yaml:"logging"`
+
+	Metrics struct {
+		// Upper bounds of the HTTP latency (seconds) and response size
+		// (bytes) histograms; the defaults of pkg/metrics when empty.
+		DurationBuckets []float64 `yaml:"duration_buckets"`
+		SizeBuckets     []float64 `yaml:"size_buckets"`
+	} `yaml:"metrics"`
+
+	Tracing struct {
+		ServiceName string `yaml:"service_name" env-default:"vk-intern-app"`
+		// Exporter is otlp-http, otlp-grpc, stdout or none.
+		Exporter string `yaml:"exporter" env-default:"otlp-http"`
+		// Endpoint is host:port of the collector. When empty, the OTLP
+		// exporters use OTEL_EXPORTER_OTLP_ENDPOINT or their own default.
+		Endpoint string `yaml:"endpoint"`
+		TLS      bool   `yaml:"tls"`
+		CAFile   string `yaml:"ca_file"`
+		// SampleRatio is the share of new traces recorded; set the exporter
+		// to none to record nothing.
+		SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+	} `yaml:"tracing"`
+
+	// HTTPClient tunes the requests the app makes to other hosts, such as
+	// fetching listing images to check them.
+	HTTPClient struct {
+		Timeout time.Duration `yaml:"timeout" env-default:"5s"`
+		// HostTimeouts override Timeout for the listed host names.
+		HostTimeouts     map[string]time.Duration `yaml:"host_timeouts"`
+		MaxRetries       int                      `yaml:"max_retries" env-default:"2"`
+		RetryBackoff     time.Duration            `yaml:"retry_backoff" env-default:"100ms"`
+		BreakerThreshold int                      `yaml:"breaker_threshold" env-default:"5"`
+		BreakerCooldown  time.Duration            `yaml:"breaker_cooldown" env-default:"30s"`
+	} `yaml:"http_client"`
+
+	JWT struct {
+		SigningKeyID string   `yaml:"signing_key_id"`
+		Keys         []JWTKey `yaml:"keys"`
+	} `yaml:"jwt"`
+
+	// Storage is either "postgres" or "memory"; the latter needs no database
+	// and forgets everything on restart.
+	Storage         string        `yaml:"storage" env-default:"postgres"`
+	DatabaseURI     string        `yaml:"db_uri"`
+	JWTSecret       string        `yaml:"jwt_secret"`
+	TokenTTL        time.Duration `yaml:"token_ttl" env-default:"15m"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
+	RevocationStore string        `yaml:"revocation_store" env-default:"postgres"`
+
+	// AutoMigrate applies the embedded migrations at startup. Without it the
+	// app refuses to start on a database that lacks any of them.
+	AutoMigrate bool `yaml:"auto_migrate" env-default:"false"`
+	// SlowQueryThreshold is how long a query may take before it is logged;
+	// zero logs none.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" env-default:"200ms"`
+}
+
+type Logging struct {
+	// Level is debug, info, warn or error. It can be changed at runtime
+	// through PUT /admin/log-level.
+	Level  string `yaml:"level" env-default:"info"`
+	Format string `yaml:"format" env-default:"json"`
+	// Output is stdout, stderr or the path of a file to append to.
+	Output string `yaml:"output" env-default:"stdout"`
+	// Redact lists attribute keys whose values never reach the logs.
+	Redact   []string `yaml:"redact" env-default:"password,token,access_token,refresh_token,authorization,username"`
+	Sampling struct {
+		// Per second and message, the first Initial debug and info records
+		// are written, then every Thereafter-th. Zero Initial keeps all.
+		Initial    int `yaml:"initial"`
+		Thereafter int `yaml:"thereafter"`
+	} `yaml:"sampling"`
+}
+
+// JWTKey points to the PEM files of one signing key. Keys that only have a
+// public part are used to verify tokens issued before a rotation.
+type JWTKey struct {
+	ID             string `yaml:"id"`
+	Algorithm      string `yaml:"algorithm"`
+	PrivateKeyPath string `yaml:"private_key_path"`
+	PublicKeyPath  string `yaml:"public_key_path"`
 }
 
 func MustLoad() *Config {
@@ -0,0 +1,62 @@
+// Package apierror is the single place where service and storage errors are
+// translated into RFC 7807 problem responses.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/justcgh9/vk-internship-application/internal/service/auth"
+	"github.com/justcgh9/vk-internship-application/internal/service/listing"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+)
+
+var mappings = []struct {
+	target  error
+	problem *httpx.Error
+}{
+	{auth.ErrInvalidCredentials, httpx.NewError(http.StatusUnauthorized, "invalid_credentials", "invalid username or password")},
+	{auth.ErrUsernameTaken, httpx.NewError(http.StatusConflict, "username_taken", "username is already taken")},
+	{auth.ErrInvalidInput, httpx.NewError(http.StatusUnprocessableEntity, "invalid_input", "username or password does not meet the requirements")},
+	{auth.ErrInvalidRefreshToken, httpx.NewError(http.StatusUnauthorized, "invalid_refresh_token", "refresh token is invalid or expired")},
+	{auth.ErrRefreshTokenReused, httpx.NewError(http.StatusUnauthorized, "refresh_token_reused", "refresh token was already used; log in again")},
+	{auth.ErrInvalidToken, httpx.NewError(http.StatusUnauthorized, "invalid_token", "access token is invalid or expired")},
+	{auth.ErrTokenRevoked, httpx.NewError(http.StatusUnauthorized, "token_revoked", "access token has been revoked")},
+	{auth.ErrUserNotFound, httpx.NewError(http.StatusNotFound, "user_not_found", "user not found")},
+	{auth.ErrInvalidRole, httpx.NewError(http.StatusUnprocessableEntity, "invalid_role", "role must be one of: user, moderator, admin")},
+
+	{listing.ErrInvalidListing, httpx.NewError(http.StatusUnprocessableEntity, "invalid_listing", "invalid listing data")},
+	{listing.ErrListingNotFound, httpx.NewError(http.StatusNotFound, "listing_not_found", "listing not found")},
+	{listing.ErrForbidden, httpx.NewError(http.StatusForbidden, "listing_forbidden", "listing belongs to another user")},
+
+	{storage.ErrInvalidCursor, httpx.ErrInvalidQuery.WithFields(httpx.FieldError{Field: "cursor", Message: "does not match sort_by and sort_order"})},
+
+	// Storage errors that a service let through without a more specific meaning.
+	{storage.ErrNotFound, httpx.ErrNotFound},
+	{storage.ErrConflict, httpx.ErrConflict},
+	{storage.ErrInvalid, httpx.ErrValidation},
+}
+
+// From returns the HTTP representation of err. Errors that already are
+// *httpx.Error are kept, known sentinels are mapped, and anything else turns
+// into an opaque internal error.
+func From(err error) *httpx.Error {
+	var herr *httpx.Error
+	if errors.As(err, &herr) {
+		return herr
+	}
+
+	for _, m := range mappings {
+		if errors.Is(err, m.target) {
+			return m.problem.Wrap(err)
+		}
+	}
+
+	return httpx.ErrInternal.Wrap(err)
+}
+
+// Write renders err as an application/problem+json response.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	httpx.WriteProblem(w, r, From(err))
+}
@@ -0,0 +1,83 @@
+package apierror_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
+	"github.com/justcgh9/vk-internship-application/internal/service/listing"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+)
+
+func TestFrom_MapsSentinels(t *testing.T) {
+	err := fmt.Errorf("update listing: %w", listing.ErrForbidden)
+
+	herr := apierror.From(err)
+
+	require.Equal(t, http.StatusForbidden, herr.Status)
+	require.Equal(t, "listing_forbidden", herr.Code)
+	require.ErrorIs(t, herr, listing.ErrForbidden)
+}
+
+func TestFrom_InvalidCursorReportsField(t *testing.T) {
+	herr := apierror.From(storage.ErrInvalidCursor)
+
+	require.Equal(t, http.StatusBadRequest, herr.Status)
+	require.Equal(t, "invalid_query", herr.Code)
+	require.Len(t, herr.Fields, 1)
+	require.Equal(t, "cursor", herr.Fields[0].Field)
+}
+
+func TestFrom_StorageFallbacks(t *testing.T) {
+	require.Equal(t, http.StatusNotFound, apierror.From(storage.ErrNotFound).Status)
+	require.Equal(t, http.StatusConflict, apierror.From(fmt.Errorf("%w: users_username_key", storage.ErrConflict)).Status)
+}
+
+func TestFrom_KeepsHTTPErrors(t *testing.T) {
+	in := httpx.ErrValidation.WithFields(httpx.FieldError{Field: "title", Message: "is required"})
+
+	herr := apierror.From(fmt.Errorf("decode: %w", in))
+
+	require.Same(t, in, herr)
+}
+
+func TestFrom_UnknownErrorIsOpaque(t *testing.T) {
+	herr := apierror.From(errors.New("pq: connection reset"))
+
+	require.Equal(t, http.StatusInternalServerError, herr.Status)
+	require.Equal(t, "internal_error", herr.Code)
+	require.NotContains(t, herr.Detail, "connection reset")
+}
+
+func TestWrite(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+
+	req := httptest.NewRequest(http.MethodGet, "/listings/42", nil)
+	req = req.WithContext(trace.ContextWithSpanContext(req.Context(), sc))
+	w := httptest.NewRecorder()
+
+	apierror.Write(w, req, listing.ErrListingNotFound)
+
+	resp := w.Result()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, httpx.ProblemContentType, resp.Header.Get("Content-Type"))
+
+	var problem httpx.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	require.Equal(t, httpx.ProblemTypePrefix+"listing_not_found", problem.Type)
+	require.Equal(t, "Not Found", problem.Title)
+	require.Equal(t, http.StatusNotFound, problem.Status)
+	require.Equal(t, "listing_not_found", problem.Code)
+	require.Equal(t, "/listings/42", problem.Instance)
+	require.Equal(t, traceID.String(), problem.TraceID)
+}
@@ -0,0 +1,40 @@
+package admin
+
+import (
+	"log/slog"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/service/auth"
+)
+
+type Handler struct {
+	authSvc   auth.AuthService
+	validator *validator.Validate
+	logLevel  *slog.LevelVar
+}
+
+func New(authSvc auth.AuthService, v *validator.Validate, logLevel *slog.LevelVar) *Handler {
+	return &Handler{
+		authSvc:   authSvc,
+		validator: v,
+		logLevel:  logLevel,
+	}
+}
+
+func (h *Handler) Routes(authSvc auth.AuthService) chi.Router {
+	r := chi.NewRouter()
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(authSvc))
+		r.Use(middleware.RequireRole(models.RoleAdmin))
+		r.Put("/users/{id}/role", h.SetRole)
+		r.Get("/log-level", h.GetLogLevel)
+		r.Put("/log-level", h.SetLogLevel)
+	})
+
+	return r
+}
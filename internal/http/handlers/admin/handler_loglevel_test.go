@@ -0,0 +1,81 @@
+package admin_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/handlers/admin"
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/service/auth"
+)
+
+func logLevelRequest(t *testing.T, method, level string) *http.Request {
+	var body bytes.Buffer
+	if level != "" {
+		require.NoError(t, json.NewEncoder(&body).Encode(map[string]string{"level": level}))
+	}
+
+	req := httptest.NewRequest(method, "/log-level", &body)
+	req.Header.Set("Authorization", "Bearer token")
+	return req
+}
+
+func adminAuth(role models.Role) *mockAuthService {
+	authSvc := new(mockAuthService)
+	authSvc.On("VerifyToken", mock.Anything, "token").Return(&auth.Claims{UserID: 1, Role: role}, nil)
+	return authSvc
+}
+
+func TestGetLogLevel(t *testing.T) {
+	authSvc := adminAuth(models.RoleAdmin)
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+
+	w := httptest.NewRecorder()
+	admin.New(authSvc, validator.New(), level).Routes(authSvc).ServeHTTP(w, logLevelRequest(t, http.MethodGet, ""))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"level":"warn"}`, w.Body.String())
+}
+
+func TestSetLogLevel(t *testing.T) {
+	authSvc := adminAuth(models.RoleAdmin)
+	level := new(slog.LevelVar)
+
+	w := httptest.NewRecorder()
+	admin.New(authSvc, validator.New(), level).Routes(authSvc).ServeHTTP(w, logLevelRequest(t, http.MethodPut, "debug"))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"level":"debug"}`, w.Body.String())
+	require.Equal(t, slog.LevelDebug, level.Level())
+}
+
+func TestSetLogLevel_InvalidLevel(t *testing.T) {
+	authSvc := adminAuth(models.RoleAdmin)
+	level := new(slog.LevelVar)
+
+	w := httptest.NewRecorder()
+	admin.New(authSvc, validator.New(), level).Routes(authSvc).ServeHTTP(w, logLevelRequest(t, http.MethodPut, "verbose"))
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.Equal(t, slog.LevelInfo, level.Level())
+}
+
+func TestSetLogLevel_ForbiddenForNonAdmins(t *testing.T) {
+	authSvc := adminAuth(models.RoleModerator)
+	level := new(slog.LevelVar)
+
+	w := httptest.NewRecorder()
+	admin.New(authSvc, validator.New(), level).Routes(authSvc).ServeHTTP(w, logLevelRequest(t, http.MethodPut, "debug"))
+
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Equal(t, slog.LevelInfo, level.Level())
+}
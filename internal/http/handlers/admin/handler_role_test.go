@@ -0,0 +1,132 @@
+package admin_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/handlers/admin"
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/service/auth"
+)
+
+type mockAuthService struct {
+	mock.Mock
+}
+
+func (m *mockAuthService) Register(ctx context.Context, username, password string) (*models.User, *auth.TokenPair, error) {
+	panic("not used in this test")
+}
+
+func (m *mockAuthService) Login(ctx context.Context, username, password string) (*auth.TokenPair, error) {
+	panic("not used in this test")
+}
+
+func (m *mockAuthService) Refresh(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
+	panic("not used in this test")
+}
+
+func (m *mockAuthService) Logout(ctx context.Context, claims *auth.Claims, refreshToken string) error {
+	panic("not used in this test")
+}
+
+func (m *mockAuthService) LogoutAll(ctx context.Context, userID int64) error {
+	panic("not used in this test")
+}
+
+func (m *mockAuthService) VerifyToken(ctx context.Context, token string) (*auth.Claims, error) {
+	args := m.Called(ctx, token)
+	if claims := args.Get(0); claims != nil {
+		return claims.(*auth.Claims), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockAuthService) JWKS() auth.JWKS {
+	panic("not used in this test")
+}
+
+func (m *mockAuthService) GetUser(ctx context.Context, id int64) (*models.User, error) {
+	panic("not used in this test")
+}
+
+func (m *mockAuthService) SetRole(ctx context.Context, id int64, role models.Role) (*models.User, error) {
+	args := m.Called(ctx, id, role)
+	if usr := args.Get(0); usr != nil {
+		return usr.(*models.User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func newRouter(authSvc *mockAuthService) chi.Router {
+	return admin.New(authSvc, validator.New(), new(slog.LevelVar)).Routes(authSvc)
+}
+
+func setRoleRequest(t *testing.T, role string) *http.Request {
+	b, err := json.Marshal(map[string]string{"role": role})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPut, "/users/5/role", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer token")
+	return req
+}
+
+func TestSetRole_Success(t *testing.T) {
+	authSvc := new(mockAuthService)
+	authSvc.On("VerifyToken", mock.Anything, "token").Return(&auth.Claims{UserID: 1, Role: models.RoleAdmin}, nil)
+	authSvc.On("SetRole", mock.Anything, int64(5), models.RoleModerator).
+		Return(&models.User{ID: 5, Username: "mod", Role: models.RoleModerator}, nil)
+
+	w := httptest.NewRecorder()
+	newRouter(authSvc).ServeHTTP(w, setRoleRequest(t, "moderator"))
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var usr models.User
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&usr))
+	require.Equal(t, models.RoleModerator, usr.Role)
+	authSvc.AssertExpectations(t)
+}
+
+func TestSetRole_ForbiddenForNonAdmins(t *testing.T) {
+	for _, role := range []models.Role{models.RoleUser, models.RoleModerator} {
+		authSvc := new(mockAuthService)
+		authSvc.On("VerifyToken", mock.Anything, "token").Return(&auth.Claims{UserID: 1, Role: role}, nil)
+
+		w := httptest.NewRecorder()
+		newRouter(authSvc).ServeHTTP(w, setRoleRequest(t, "admin"))
+
+		require.Equal(t, http.StatusForbidden, w.Code)
+		authSvc.AssertNotCalled(t, "SetRole", mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
+func TestSetRole_InvalidRole(t *testing.T) {
+	authSvc := new(mockAuthService)
+	authSvc.On("VerifyToken", mock.Anything, "token").Return(&auth.Claims{UserID: 1, Role: models.RoleAdmin}, nil)
+
+	w := httptest.NewRecorder()
+	newRouter(authSvc).ServeHTTP(w, setRoleRequest(t, "owner"))
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestSetRole_UserNotFound(t *testing.T) {
+	authSvc := new(mockAuthService)
+	authSvc.On("VerifyToken", mock.Anything, "token").Return(&auth.Claims{UserID: 1, Role: models.RoleAdmin}, nil)
+	authSvc.On("SetRole", mock.Anything, int64(5), models.RoleUser).Return(nil, auth.ErrUserNotFound)
+
+	w := httptest.NewRecorder()
+	newRouter(authSvc).ServeHTTP(w, setRoleRequest(t, "user"))
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+type LogLevelRequest struct {
+	Level string `json:"level" validate:"required,oneof=debug info warn error"`
+}
+
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+func (h *Handler) GetLogLevel(w http.ResponseWriter, _ *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, LogLevelResponse{Level: levelName(h.logLevel.Level())})
+}
+
+// SetLogLevel changes the level of the app logs until the next restart.
+func (h *Handler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	ctx, span := otel.Tracer("vk-intern-app").Start(r.Context(), "admin.set_log_level")
+	defer span.End()
+
+	log := logger.
+		FromContext(ctx).
+		With("component", "handler").
+		With("function", "set_log_level")
+
+	var req LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("error decoding request body", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid JSON")
+		apierror.Write(w, r, httpx.ErrInvalidJSON.Wrap(err))
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		log.Error("validation failed", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "validation failed")
+		apierror.Write(w, r, httpx.ErrValidation.WithFields(httpx.FieldErrors(err, req)...))
+		return
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+		log.Error("error parsing level", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid level")
+		apierror.Write(w, r, httpx.ErrValidation.Wrap(err))
+		return
+	}
+
+	previous := h.logLevel.Level()
+	h.logLevel.Set(level)
+
+	// Logged at warn so that the change is recorded whatever the new level.
+	log.Warn("log level changed", slog.String("from", levelName(previous)), slog.String("to", levelName(level)))
+	span.SetAttributes(attribute.String("admin.log_level", levelName(level)))
+	span.SetStatus(codes.Ok, "log level changed")
+
+	httpx.WriteJSON(w, http.StatusOK, LogLevelResponse{Level: levelName(level)})
+}
+
+func levelName(l slog.Level) string {
+	return strings.ToLower(l.String())
+}
@@ -0,0 +1,81 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+var errInvalidUserID = httpx.NewError(http.StatusBadRequest, "invalid_user_id", "user id must be an integer")
+
+type SetRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
+func (h *Handler) SetRole(w http.ResponseWriter, r *http.Request) {
+	ctx, span := otel.Tracer("vk-intern-app").Start(r.Context(), "admin.set_role")
+	defer span.End()
+
+	log := logger.
+		FromContext(ctx).
+		With("component", "handler").
+		With("function", "set_role")
+
+	log.Info("set role request received", slog.String("method", r.Method), slog.String("url", r.RequestURI))
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Warn("invalid user id", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid user id")
+		apierror.Write(w, r, errInvalidUserID.Wrap(err))
+		return
+	}
+
+	var req SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("error decoding request body", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid JSON")
+		apierror.Write(w, r, httpx.ErrInvalidJSON.Wrap(err))
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		log.Error("validation failed", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "validation failed")
+		apierror.Write(w, r, httpx.ErrValidation.WithFields(httpx.FieldErrors(err, req)...))
+		return
+	}
+
+	span.SetAttributes(
+		attribute.Int64("admin.target_user_id", userID),
+		attribute.String("admin.role", req.Role),
+	)
+
+	user, err := h.authSvc.SetRole(ctx, userID, models.Role(req.Role))
+	if err != nil {
+		log.Error("error setting role", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "set role failed")
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info("role updated", slog.Int64("target_user_id", userID), slog.String("role", req.Role))
+	span.SetStatus(codes.Ok, "role updated")
+
+	httpx.WriteJSON(w, http.StatusOK, user)
+}
@@ -33,5 +33,13 @@ func (h *Handler) Routes(authSvc auth.AuthService) chi.Router {
 		r.Post("/login", h.Login)
 	})
 
+	r.Post("/refresh", h.Refresh)
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(authSvc))
+		r.Post("/logout", h.Logout)
+		r.Post("/logout/all", h.LogoutAll)
+	})
+
 	return r
 }
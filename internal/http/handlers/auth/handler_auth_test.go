@@ -14,30 +14,57 @@ import (
 	"github.com/stretchr/testify/require"
 
 	authHandlers "github.com/justcgh9/vk-internship-application/internal/http/handlers/auth"
+	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
 	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/service/auth"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
 )
 
 type mockAuthService struct {
 	mock.Mock
 }
 
-func (m *mockAuthService) Register(ctx context.Context, username, password string) (*models.User, string, error) {
+func (m *mockAuthService) Register(ctx context.Context, username, password string) (*models.User, *auth.TokenPair, error) {
 	args := m.Called(ctx, username, password)
-	return args.Get(0).(*models.User), args.String(1), args.Error(2)
+	return args.Get(0).(*models.User), args.Get(1).(*auth.TokenPair), args.Error(2)
 }
 
-func (m *mockAuthService) Login(ctx context.Context, username, password string) (string, error) {
+func (m *mockAuthService) Login(ctx context.Context, username, password string) (*auth.TokenPair, error) {
 	args := m.Called(ctx, username, password)
-	return args.String(0), args.Error(1)
+	return args.Get(0).(*auth.TokenPair), args.Error(1)
+}
+
+func (m *mockAuthService) Refresh(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
+	args := m.Called(ctx, refreshToken)
+	return args.Get(0).(*auth.TokenPair), args.Error(1)
 }
 
 func (m *mockAuthService) GetUser(ctx context.Context, id int64) (*models.User, error) {
 	panic("not needed")
 }
 
-func (m *mockAuthService) VerifyToken(token string) (int64, error) {
-	args := m.Called(token)
-	return args.Get(0).(int64), args.Error(1)
+func (m *mockAuthService) SetRole(ctx context.Context, id int64, role models.Role) (*models.User, error) {
+	panic("not needed")
+}
+
+func (m *mockAuthService) Logout(ctx context.Context, claims *auth.Claims, refreshToken string) error {
+	args := m.Called(ctx, claims, refreshToken)
+	return args.Error(0)
+}
+
+func (m *mockAuthService) LogoutAll(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *mockAuthService) VerifyToken(ctx context.Context, token string) (*auth.Claims, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(*auth.Claims), args.Error(1)
+}
+
+func (m *mockAuthService) JWKS() auth.JWKS {
+	args := m.Called()
+	return args.Get(0).(auth.JWKS)
 }
 
 func TestRegister_Success(t *testing.T) {
@@ -55,11 +82,11 @@ func TestRegister_Success(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	user := &models.User{ID: 1, Username: "newuser"}
-	token := "mocked-token"
+	tokens := &auth.TokenPair{AccessToken: "mocked-token", RefreshToken: "mocked-refresh"}
 
 	authSvc.
 		On("Register", mock.Anything, "newuser", "securepass123").
-		Return(user, token, nil)
+		Return(user, tokens, nil)
 
 	handler.Register(w, req)
 
@@ -71,7 +98,8 @@ func TestRegister_Success(t *testing.T) {
 	var out map[string]interface{}
 	err := json.NewDecoder(resp.Body).Decode(&out)
 	require.NoError(t, err)
-	require.Equal(t, token, out["token"])
+	require.Equal(t, tokens.AccessToken, out["token"])
+	require.Equal(t, tokens.RefreshToken, out["refresh_token"])
 	require.Equal(t, "newuser", out["user"].(map[string]interface{})["username"])
 }
 
@@ -120,13 +148,35 @@ func TestRegister_ServiceError(t *testing.T) {
 
 	authSvc.
 		On("Register", mock.Anything, "validuser", "validpass123").
-		Return(&models.User{}, "", errors.New("registration failed"))
+		Return(&models.User{}, (*auth.TokenPair)(nil), errors.New("registration failed"))
 
 	handler.Register(w, req)
 
 	require.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
 }
 
+func TestRegister_UsernameTaken(t *testing.T) {
+	authSvc := new(mockAuthService)
+	handler := authHandlers.New(authSvc, validator.New())
+
+	b, _ := json.Marshal(map[string]string{"username": "validuser", "password": "validpass123"})
+	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewReader(b))
+	w := httptest.NewRecorder()
+
+	authSvc.
+		On("Register", mock.Anything, "validuser", "validpass123").
+		Return((*models.User)(nil), (*auth.TokenPair)(nil), auth.ErrUsernameTaken)
+
+	handler.Register(w, req)
+
+	resp := w.Result()
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	var problem httpx.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	require.Equal(t, "username_taken", problem.Code)
+}
+
 func TestLogin_Success(t *testing.T) {
 	authSvc := new(mockAuthService)
 	handler := authHandlers.New(authSvc, validator.New())
@@ -142,7 +192,7 @@ func TestLogin_Success(t *testing.T) {
 
 	authSvc.
 		On("Login", mock.Anything, "existinguser", "correctpass").
-		Return("jwt-token", nil)
+		Return(&auth.TokenPair{AccessToken: "jwt-token", RefreshToken: "refresh-token"}, nil)
 
 	handler.Login(w, req)
 
@@ -155,6 +205,7 @@ func TestLogin_Success(t *testing.T) {
 	err := json.NewDecoder(resp.Body).Decode(&out)
 	require.NoError(t, err)
 	require.Equal(t, "jwt-token", out["token"])
+	require.Equal(t, "refresh-token", out["refresh_token"])
 }
 
 func TestLogin_InvalidJSON(t *testing.T) {
@@ -202,9 +253,184 @@ func TestLogin_Unauthorized(t *testing.T) {
 
 	authSvc.
 		On("Login", mock.Anything, "wronguser", "wrongpass").
-		Return("", errors.New("invalid credentials"))
+		Return((*auth.TokenPair)(nil), auth.ErrInvalidCredentials)
 
 	handler.Login(w, req)
 
+	resp := w.Result()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, httpx.ProblemContentType, resp.Header.Get("Content-Type"))
+
+	var problem httpx.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	require.Equal(t, "invalid_credentials", problem.Code)
+	require.Equal(t, "/login", problem.Instance)
+}
+
+func TestLogin_StorageFailure(t *testing.T) {
+	authSvc := new(mockAuthService)
+	handler := authHandlers.New(authSvc, validator.New())
+
+	b, _ := json.Marshal(map[string]string{"username": "user", "password": "password"})
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(b))
+	w := httptest.NewRecorder()
+
+	authSvc.
+		On("Login", mock.Anything, "user", "password").
+		Return((*auth.TokenPair)(nil), errors.New("connection refused"))
+
+	handler.Login(w, req)
+
+	resp := w.Result()
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	var problem httpx.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	require.Equal(t, "internal_error", problem.Code)
+	require.NotContains(t, problem.Detail, "connection refused")
+}
+
+func TestRefresh_Success(t *testing.T) {
+	authSvc := new(mockAuthService)
+	handler := authHandlers.New(authSvc, validator.New())
+
+	b, _ := json.Marshal(map[string]string{"refresh_token": "old-refresh"})
+
+	req := httptest.NewRequest(http.MethodPost, "/refresh", bytes.NewReader(b))
+	w := httptest.NewRecorder()
+
+	authSvc.
+		On("Refresh", mock.Anything, "old-refresh").
+		Return(&auth.TokenPair{AccessToken: "new-access", RefreshToken: "new-refresh"}, nil)
+
+	handler.Refresh(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var out map[string]string
+	err := json.NewDecoder(resp.Body).Decode(&out)
+	require.NoError(t, err)
+	require.Equal(t, "new-access", out["token"])
+	require.Equal(t, "new-refresh", out["refresh_token"])
+}
+
+func TestRefresh_ValidationError(t *testing.T) {
+	authSvc := new(mockAuthService)
+	handler := authHandlers.New(authSvc, validator.New())
+
+	req := httptest.NewRequest(http.MethodPost, "/refresh", bytes.NewReader([]byte(`{}`)))
+	w := httptest.NewRecorder()
+
+	handler.Refresh(w, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+}
+
+func TestRefresh_Reused(t *testing.T) {
+	authSvc := new(mockAuthService)
+	handler := authHandlers.New(authSvc, validator.New())
+
+	b, _ := json.Marshal(map[string]string{"refresh_token": "stolen-refresh"})
+
+	req := httptest.NewRequest(http.MethodPost, "/refresh", bytes.NewReader(b))
+	w := httptest.NewRecorder()
+
+	authSvc.
+		On("Refresh", mock.Anything, "stolen-refresh").
+		Return((*auth.TokenPair)(nil), auth.ErrRefreshTokenReused)
+
+	handler.Refresh(w, req)
+
 	require.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
 }
+
+func TestLogout_Success(t *testing.T) {
+	authSvc := new(mockAuthService)
+	handler := authHandlers.New(authSvc, validator.New())
+
+	claims := &auth.Claims{UserID: 3, TokenID: "jti"}
+	b, _ := json.Marshal(map[string]string{"refresh_token": "refresh"})
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", bytes.NewReader(b))
+	req = req.WithContext(middleware.WithClaims(context.Background(), claims))
+	w := httptest.NewRecorder()
+
+	authSvc.On("Logout", mock.Anything, claims, "refresh").Return(nil)
+
+	handler.Logout(w, req)
+
+	require.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+	authSvc.AssertExpectations(t)
+}
+
+func TestLogout_EmptyBody(t *testing.T) {
+	authSvc := new(mockAuthService)
+	handler := authHandlers.New(authSvc, validator.New())
+
+	claims := &auth.Claims{UserID: 3, TokenID: "jti"}
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req = req.WithContext(middleware.WithClaims(context.Background(), claims))
+	w := httptest.NewRecorder()
+
+	authSvc.On("Logout", mock.Anything, claims, "").Return(nil)
+
+	handler.Logout(w, req)
+
+	require.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+}
+
+func TestLogoutAll_Success(t *testing.T) {
+	authSvc := new(mockAuthService)
+	handler := authHandlers.New(authSvc, validator.New())
+
+	req := httptest.NewRequest(http.MethodPost, "/logout/all", nil)
+	req = req.WithContext(middleware.WithUserID(context.Background(), 3))
+	w := httptest.NewRecorder()
+
+	authSvc.On("LogoutAll", mock.Anything, int64(3)).Return(nil)
+
+	handler.LogoutAll(w, req)
+
+	require.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+}
+
+func TestLogoutAll_ServiceError(t *testing.T) {
+	authSvc := new(mockAuthService)
+	handler := authHandlers.New(authSvc, validator.New())
+
+	req := httptest.NewRequest(http.MethodPost, "/logout/all", nil)
+	req = req.WithContext(middleware.WithUserID(context.Background(), 3))
+	w := httptest.NewRecorder()
+
+	authSvc.On("LogoutAll", mock.Anything, int64(3)).Return(errors.New("db down"))
+
+	handler.LogoutAll(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
+
+func TestJWKS(t *testing.T) {
+	authSvc := new(mockAuthService)
+	handler := authHandlers.New(authSvc, validator.New())
+
+	authSvc.On("JWKS").Return(auth.JWKS{Keys: []auth.JWK{{KeyType: "OKP", KeyID: "k1", Curve: "Ed25519", X: "abc"}}})
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.JWKS(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var out auth.JWKS
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	require.Len(t, out.Keys, 1)
+	require.Equal(t, "k1", out.Keys[0].KeyID)
+}
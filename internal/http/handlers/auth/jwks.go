@@ -0,0 +1,13 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+)
+
+// JWKS publishes the public keys other services use to verify our tokens.
+func (h *Handler) JWKS(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	httpx.WriteJSON(w, http.StatusOK, h.authSvc.JWKS())
+}
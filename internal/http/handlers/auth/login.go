@@ -9,6 +9,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
 	"github.com/justcgh9/vk-internship-application/pkg/httpx"
 	"github.com/justcgh9/vk-internship-application/pkg/logger"
 )
@@ -19,7 +20,8 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
@@ -38,7 +40,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		log.Error("error decoding request body", slog.String("err", err.Error()))
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "invalid JSON")
-		http.Error(w, "invalid JSON", http.StatusUnprocessableEntity)
+		apierror.Write(w, r, httpx.ErrInvalidJSON.Wrap(err))
 		return
 	}
 
@@ -46,23 +48,26 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		log.Error("error validating request body", slog.String("err", err.Error()))
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "validation failed")
-		http.Error(w, "login and password cannot be empty", http.StatusUnprocessableEntity)
+		apierror.Write(w, r, httpx.ErrValidation.WithFields(httpx.FieldErrors(err, req)...))
 		return
 	}
 
 	span.SetAttributes(attribute.String("auth.username", req.Username))
 
-	token, err := h.authSvc.Login(ctx, req.Username, req.Password)
+	tokens, err := h.authSvc.Login(ctx, req.Username, req.Password)
 	if err != nil {
 		log.Error("error authorizing user", slog.String("err", err.Error()))
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "login failed")
-		http.Error(w, "unauthorized: invalid login or password", http.StatusUnauthorized)
+		apierror.Write(w, r, err)
 		return
 	}
 
 	log.Info("attempt succeeded")
 	span.SetStatus(codes.Ok, "login successful")
 
-	httpx.WriteJSON(w, http.StatusOK, LoginResponse{Token: token})
+	httpx.WriteJSON(w, http.StatusOK, LoginResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
 }
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
+	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx, span := otel.Tracer("vk-intern-app").Start(r.Context(), "auth.logout")
+	defer span.End()
+
+	log := logger.
+		FromContext(ctx).
+		With("component", "handler").
+		With("function", "logout")
+
+	log.Info("logout attempt", slog.String("method", r.Method), slog.String("url", r.RequestURI))
+
+	claims, ok := middleware.GetClaims(ctx)
+	if !ok {
+		log.Warn("unauthorized request - no claims in context")
+		span.SetStatus(codes.Error, "unauthorized")
+		apierror.Write(w, r, httpx.ErrUnauthorized)
+		return
+	}
+	span.SetAttributes(attribute.Int64("auth.user_id", claims.UserID))
+
+	// The body is optional: without a refresh token only the access token is revoked.
+	var req LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		log.Error("error decoding request body", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid JSON")
+		apierror.Write(w, r, httpx.ErrInvalidJSON.Wrap(err))
+		return
+	}
+
+	if err := h.authSvc.Logout(ctx, claims, req.RefreshToken); err != nil {
+		log.Error("error logging out", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "logout failed")
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info("logout succeeded")
+	span.SetStatus(codes.Ok, "logout successful")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	ctx, span := otel.Tracer("vk-intern-app").Start(r.Context(), "auth.logout_all")
+	defer span.End()
+
+	log := logger.
+		FromContext(ctx).
+		With("component", "handler").
+		With("function", "logout_all")
+
+	log.Info("logout everywhere attempt", slog.String("method", r.Method), slog.String("url", r.RequestURI))
+
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		log.Warn("unauthorized request - no user ID in context")
+		span.SetStatus(codes.Error, "unauthorized")
+		apierror.Write(w, r, httpx.ErrUnauthorized)
+		return
+	}
+	span.SetAttributes(attribute.Int64("auth.user_id", userID))
+
+	if err := h.authSvc.LogoutAll(ctx, userID); err != nil {
+		log.Error("error logging out everywhere", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "logout failed")
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info("logout everywhere succeeded")
+	span.SetStatus(codes.Ok, "logout successful")
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type RefreshResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	ctx, span := otel.Tracer("vk-intern-app").Start(r.Context(), "auth.refresh")
+	defer span.End()
+
+	log := logger.
+		FromContext(ctx).
+		With("component", "handler").
+		With("function", "refresh")
+
+	log.Info("refresh attempt", slog.String("method", r.Method), slog.String("url", r.RequestURI))
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("error decoding request body", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid JSON")
+		apierror.Write(w, r, httpx.ErrInvalidJSON.Wrap(err))
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		log.Error("error validating request body", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "validation failed")
+		apierror.Write(w, r, httpx.ErrValidation.WithFields(httpx.FieldErrors(err, req)...))
+		return
+	}
+
+	tokens, err := h.authSvc.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		log.Error("error refreshing tokens", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "refresh failed")
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info("refresh succeeded")
+	span.SetStatus(codes.Ok, "refresh successful")
+
+	httpx.WriteJSON(w, http.StatusOK, RefreshResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
 	"github.com/justcgh9/vk-internship-application/internal/models"
 	"github.com/justcgh9/vk-internship-application/pkg/httpx"
 	"github.com/justcgh9/vk-internship-application/pkg/logger"
@@ -20,8 +21,9 @@ type RegisterRequest struct {
 }
 
 type RegisterResponse struct {
-	User  *models.User `json:"user"`
-	Token string       `json:"token"`
+	User         *models.User `json:"user"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
 }
 
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
@@ -40,25 +42,25 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		log.Error("error decoding request body", slog.String("err", err.Error()))
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "invalid JSON")
-		http.Error(w, "invalid JSON", http.StatusUnprocessableEntity)
+		apierror.Write(w, r, httpx.ErrInvalidJSON.Wrap(err))
 		return
 	}
 	if err := h.validator.Struct(req); err != nil {
 		log.Error("validation failed", slog.String("err", err.Error()))
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "validation failed")
-		http.Error(w, "username and password must meet validation constraints", http.StatusUnprocessableEntity)
+		apierror.Write(w, r, httpx.ErrValidation.WithFields(httpx.FieldErrors(err, req)...))
 		return
 	}
 
 	span.SetAttributes(attribute.String("auth.username", req.Username))
 
-	user, token, err := h.authSvc.Register(ctx, req.Username, req.Password)
+	user, tokens, err := h.authSvc.Register(ctx, req.Username, req.Password)
 	if err != nil {
 		log.Error("error registering user", slog.String("err", err.Error()))
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "register failed")
-		http.Error(w, "could not register user", http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -67,7 +69,8 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	span.SetStatus(codes.Ok, "register successful")
 
 	httpx.WriteJSON(w, http.StatusCreated, RegisterResponse{
-		User:  user,
-		Token: token,
+		User:         user,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	})
 }
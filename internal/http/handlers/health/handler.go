@@ -0,0 +1,126 @@
+// Package health serves the probes orchestrators use to decide whether to
+// restart the app and whether to send it traffic.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+// Check is one dependency readiness depends on. A failing optional check is
+// reported but does not take the app out of rotation: losing traces is no
+// reason to stop serving requests.
+type Check struct {
+	Name     string
+	Fn       func(ctx context.Context) error
+	Optional bool
+}
+
+const (
+	statusOK       = "ok"
+	statusFailing  = "failing"
+	statusDraining = "draining"
+)
+
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type Handler struct {
+	checks  []Check
+	timeout time.Duration
+
+	started  atomic.Bool
+	draining atomic.Bool
+}
+
+// New returns a handler that runs checks on every readiness probe, each
+// bounded by timeout.
+func New(timeout time.Duration, checks ...Check) *Handler {
+	return &Handler{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// Drain makes readiness fail from now on, so that load balancers stop
+// sending traffic before the server shuts down.
+func (h *Handler) Drain() {
+	h.draining.Store(true)
+}
+
+// Live reports that the process is up and serving HTTP. It checks no
+// dependencies: restarting the app would not bring a database back.
+func (h *Handler) Live(w http.ResponseWriter, _ *http.Request) {
+	httpx.WriteJSON(w, http.StatusOK, Response{Status: statusOK})
+}
+
+// Ready reports whether the app can serve requests right now.
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		httpx.WriteJSON(w, http.StatusServiceUnavailable, Response{Status: statusDraining})
+		return
+	}
+
+	resp, ok := h.run(r.Context())
+	if !ok {
+		httpx.WriteJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+	h.started.Store(true)
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// Started reports whether the dependencies have been ready at least once.
+// Unlike readiness it never fails again afterwards, so a slow first
+// connection delays the other probes instead of getting the app restarted.
+func (h *Handler) Started(w http.ResponseWriter, r *http.Request) {
+	if h.started.Load() {
+		httpx.WriteJSON(w, http.StatusOK, Response{Status: statusOK})
+		return
+	}
+	h.Ready(w, r)
+}
+
+// run executes the checks concurrently and reports whether every required
+// one passed.
+func (h *Handler) run(ctx context.Context) (Response, bool) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	log := logger.FromContext(ctx).With("component", "health")
+
+	errs := make([]error, len(h.checks))
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = c.Fn(ctx)
+		}()
+	}
+	wg.Wait()
+
+	resp := Response{Status: statusOK, Checks: make(map[string]string, len(h.checks))}
+	ok := true
+	for i, c := range h.checks {
+		if errs[i] == nil {
+			resp.Checks[c.Name] = statusOK
+			continue
+		}
+		log.Warn("health check failed", "check", c.Name, "optional", c.Optional, "err", errs[i])
+		resp.Checks[c.Name] = statusFailing
+		if !c.Optional {
+			ok = false
+			resp.Status = statusFailing
+		}
+	}
+	return resp, ok
+}
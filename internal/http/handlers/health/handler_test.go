@@ -0,0 +1,124 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/handlers/health"
+)
+
+func ok(context.Context) error { return nil }
+
+func failing(context.Context) error { return errors.New("connection refused") }
+
+func probe(t *testing.T, h http.HandlerFunc) (int, health.Response) {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var resp health.Response
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	return rr.Code, resp
+}
+
+func TestLive(t *testing.T) {
+	h := health.New(time.Second, health.Check{Name: "postgres", Fn: failing})
+
+	code, resp := probe(t, h.Live)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", resp.Status)
+}
+
+func TestReady(t *testing.T) {
+	h := health.New(time.Second,
+		health.Check{Name: "postgres", Fn: ok},
+		health.Check{Name: "migrations", Fn: ok},
+	)
+
+	code, resp := probe(t, h.Ready)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.Response{
+		Status: "ok",
+		Checks: map[string]string{"postgres": "ok", "migrations": "ok"},
+	}, resp)
+}
+
+func TestReady_FailingCheck(t *testing.T) {
+	h := health.New(time.Second,
+		health.Check{Name: "postgres", Fn: failing},
+		health.Check{Name: "migrations", Fn: ok},
+	)
+
+	code, resp := probe(t, h.Ready)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "failing", resp.Status)
+	assert.Equal(t, "failing", resp.Checks["postgres"])
+	assert.Equal(t, "ok", resp.Checks["migrations"])
+}
+
+func TestReady_FailingOptionalCheck(t *testing.T) {
+	h := health.New(time.Second,
+		health.Check{Name: "postgres", Fn: ok},
+		health.Check{Name: "tracing", Fn: failing, Optional: true},
+	)
+
+	code, resp := probe(t, h.Ready)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", resp.Status)
+	assert.Equal(t, "failing", resp.Checks["tracing"])
+}
+
+func TestReady_Timeout(t *testing.T) {
+	h := health.New(10*time.Millisecond, health.Check{Name: "postgres", Fn: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	code, _ := probe(t, h.Ready)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestReady_Draining(t *testing.T) {
+	h := health.New(time.Second, health.Check{Name: "postgres", Fn: ok})
+	h.Drain()
+
+	code, resp := probe(t, h.Ready)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "draining", resp.Status)
+
+	code, _ = probe(t, h.Live)
+	assert.Equal(t, http.StatusOK, code, "draining must not get the process restarted")
+}
+
+func TestStarted(t *testing.T) {
+	up := false
+	h := health.New(time.Second, health.Check{Name: "postgres", Fn: func(context.Context) error {
+		if !up {
+			return errors.New("not yet")
+		}
+		return nil
+	}})
+
+	code, _ := probe(t, h.Started)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	up = true
+	code, _ = probe(t, h.Started)
+	assert.Equal(t, http.StatusOK, code)
+
+	// Once started, later failures are left to readiness.
+	up = false
+	code, _ = probe(t, h.Started)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = probe(t, h.Ready)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
 	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
 	"github.com/justcgh9/vk-internship-application/internal/models"
 	"github.com/justcgh9/vk-internship-application/pkg/httpx"
@@ -22,10 +24,6 @@ type CreateListingRequest struct {
 	Price       float64 `json:"price" validate:"required,gt=0"`
 }
 
-const (
-	MaxImageSize = 5 * 1024 * 1024
-)
-
 func (h *Handler) CreateListing(w http.ResponseWriter, r *http.Request) {
 	ctx, span := otel.Tracer("vk-intern-app").Start(r.Context(), "listings.create")
 	defer span.End()
@@ -42,7 +40,7 @@ func (h *Handler) CreateListing(w http.ResponseWriter, r *http.Request) {
 		log.Error("error decoding request body", slog.String("err", err.Error()))
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "invalid JSON")
-		http.Error(w, "invalid JSON", http.StatusUnprocessableEntity)
+		apierror.Write(w, r, httpx.ErrInvalidJSON.Wrap(err))
 		return
 	}
 
@@ -50,7 +48,7 @@ func (h *Handler) CreateListing(w http.ResponseWriter, r *http.Request) {
 		log.Error("validation failed", slog.String("err", err.Error()))
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "validation failed")
-		http.Error(w, "invalid listing data", http.StatusUnprocessableEntity)
+		apierror.Write(w, r, httpx.ErrValidation.WithFields(httpx.FieldErrors(err, req)...))
 		return
 	}
 
@@ -58,7 +56,7 @@ func (h *Handler) CreateListing(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		log.Warn("unauthorized request - no user ID in context")
 		span.SetStatus(codes.Error, "unauthorized")
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		apierror.Write(w, r, httpx.ErrUnauthorized)
 		return
 	}
 	span.SetAttributes(
@@ -68,29 +66,14 @@ func (h *Handler) CreateListing(w http.ResponseWriter, r *http.Request) {
 		attribute.Float64("listing.price", req.Price),
 	)
 
-	resp, err := http.Get(req.ImageURL)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		log.Warn("image URL request failed or non-200", slog.String("err", err.Error()), slog.Int("status", resp.StatusCode))
+	start := time.Now()
+	err := h.checkImage(ctx, req.ImageURL)
+	h.metrics.ImageChecked(imageResult(err), time.Since(start))
+	if err != nil {
+		log.Warn("image validation failed", slog.String("err", err.Error()))
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "image URL fetch failed")
-		http.Error(w, "invalid image URL", http.StatusBadRequest)
-		return
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	contentType := resp.Header.Get("Content-Type")
-	if contentType != "image/jpeg" && contentType != "image/png" {
-		log.Warn("unsupported image format", slog.String("content_type", contentType))
-		span.SetStatus(codes.Error, "unsupported image format")
-		http.Error(w, "unsupported image format", http.StatusBadRequest)
-		return
-	}
-
-	if size := resp.ContentLength; size > MaxImageSize {
-		log.Warn("image too large", slog.Int64("size", size))
-		span.SetAttributes(attribute.Int64("image.size", size))
-		span.SetStatus(codes.Error, "image too large")
-		http.Error(w, "image too large", http.StatusBadRequest)
+		span.SetStatus(codes.Error, "image validation failed")
+		apierror.Write(w, r, imageProblem(err))
 		return
 	}
 
@@ -107,26 +90,11 @@ func (h *Handler) CreateListing(w http.ResponseWriter, r *http.Request) {
 		log.Error("failed to create listing", slog.String("err", err.Error()))
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "db create failed")
-		http.Error(w, "failed to create listing", http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	response := &models.ListingWithAuthor{
-		ID:          created.ID,
-		Title:       created.Title,
-		Description: created.Description,
-		ImageURL:    created.ImageURL,
-		Price:       created.Price,
-		IsOwned:     true,
-		CreatedAt:   created.CreatedAt,
-	}
-
-	user, err := h.authSvc.GetUser(ctx, userID)
-	if err == nil {
-		response.AuthorLogin = user.Username
-	} else {
-		log.Warn("failed to fetch author info", slog.String("err", err.Error()))
-	}
+	response := h.withAuthor(ctx, created, &userID)
 
 	log.Info("listing created", slog.Int64("listing_id", created.ID), slog.Int64("user_id", userID))
 	span.SetAttributes(attribute.Int64("listing.id", created.ID))
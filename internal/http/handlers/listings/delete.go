@@ -0,0 +1,61 @@
+package listings
+
+import (
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
+	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+func (h *Handler) DeleteListing(w http.ResponseWriter, r *http.Request) {
+	ctx, span := otel.Tracer("vk-intern-app").Start(r.Context(), "listings.delete")
+	defer span.End()
+
+	log := logger.
+		FromContext(ctx).
+		With("component", "handler").
+		With("function", "delete_listing")
+
+	log.Info("delete listing request received", slog.String("method", r.Method), slog.String("url", r.RequestURI))
+
+	id, err := parseListingID(r)
+	if err != nil {
+		log.Warn("invalid listing id", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid listing id")
+		apierror.Write(w, r, errInvalidListingID.Wrap(err))
+		return
+	}
+
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		log.Warn("unauthorized request - no user ID in context")
+		span.SetStatus(codes.Error, "unauthorized")
+		apierror.Write(w, r, httpx.ErrUnauthorized)
+		return
+	}
+	span.SetAttributes(
+		attribute.Int64("user.id", userID),
+		attribute.Int64("listing.id", id),
+	)
+
+	if err := h.listingSvc.Delete(ctx, userID, middleware.GetRole(ctx), id); err != nil {
+		log.Warn("failed to delete listing", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "listing delete failed")
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info("listing deleted", slog.Int64("listing_id", id), slog.Int64("user_id", userID))
+	span.SetStatus(codes.Ok, "listing deleted")
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,90 @@
+package listings
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
+	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+func (h *Handler) GetListing(w http.ResponseWriter, r *http.Request) {
+	ctx, span := otel.Tracer("vk-intern-app").Start(r.Context(), "listings.get")
+	defer span.End()
+
+	log := logger.
+		FromContext(ctx).
+		With("component", "handler").
+		With("function", "get_listing")
+
+	log.Info("get listing request received", slog.String("method", r.Method), slog.String("url", r.RequestURI))
+
+	id, err := parseListingID(r)
+	if err != nil {
+		log.Warn("invalid listing id", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid listing id")
+		apierror.Write(w, r, errInvalidListingID.Wrap(err))
+		return
+	}
+	span.SetAttributes(attribute.Int64("listing.id", id))
+
+	l, err := h.listingSvc.Get(ctx, id)
+	if err != nil {
+		log.Warn("failed to get listing", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "listing lookup failed")
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var viewerID *int64
+	if userID, ok := middleware.GetUserID(ctx); ok {
+		viewerID = &userID
+		span.SetAttributes(attribute.Int64("listings.viewer_id", userID))
+	}
+
+	log.Info("listing fetched", slog.Int64("listing_id", id))
+	span.SetStatus(codes.Ok, "listing fetched")
+
+	httpx.WriteJSON(w, http.StatusOK, h.withAuthor(ctx, l, viewerID))
+}
+
+// withAuthor converts a listing into its public representation, resolving the
+// author's login on a best-effort basis.
+func (h *Handler) withAuthor(ctx context.Context, l *models.Listing, viewerID *int64) *models.ListingWithAuthor {
+	response := &models.ListingWithAuthor{
+		ID:          l.ID,
+		Title:       l.Title,
+		Description: l.Description,
+		ImageURL:    l.ImageURL,
+		Price:       l.Price,
+		IsOwned:     viewerID != nil && *viewerID == l.UserID,
+		CreatedAt:   l.CreatedAt,
+	}
+
+	user, err := h.authSvc.GetUser(ctx, l.UserID)
+	if err == nil {
+		response.AuthorLogin = user.Username
+	} else {
+		logger.FromContext(ctx).Warn("failed to fetch author info", slog.String("err", err.Error()))
+	}
+
+	return response
+}
+
+var errInvalidListingID = httpx.NewError(http.StatusBadRequest, "invalid_listing_id", "listing id must be an integer")
+
+func parseListingID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+}
@@ -1,6 +1,8 @@
 package listings
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 
@@ -13,13 +15,23 @@ type Handler struct {
 	authSvc    auth.AuthService
 	listingSvc listing.Service
 	validator  *validator.Validate
+	metrics    Metrics
+	client     HTTPClient
+}
+
+// HTTPClient sends the requests that check image URLs; *httpclient.Client in
+// production.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
 }
 
-func New(authSvc auth.AuthService, listingSvc listing.Service, v *validator.Validate) *Handler {
+func New(authSvc auth.AuthService, listingSvc listing.Service, v *validator.Validate, metrics Metrics, client HTTPClient) *Handler {
 	return &Handler{
 		authSvc:    authSvc,
 		listingSvc: listingSvc,
 		validator:  v,
+		metrics:    metrics,
+		client:     client,
 	}
 }
 
@@ -29,11 +41,14 @@ func (h *Handler) Routes(authSvc auth.AuthService) chi.Router {
 	r.Group(func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware(authSvc))
 		r.Post("/", h.CreateListing)
+		r.Patch("/{id}", h.UpdateListing)
+		r.Delete("/{id}", h.DeleteListing)
 	})
 
 	r.Group(func(r chi.Router) {
 		r.Use(middleware.OptionalAuthMiddleware(authSvc))
 		r.Get("/", h.ListListings)
+		r.Get("/{id}", h.GetListing)
 	})
 
 	return r
@@ -16,7 +16,10 @@ import (
 	"github.com/justcgh9/vk-internship-application/internal/http/handlers/listings"
 	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
 	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/service/auth"
+	"github.com/justcgh9/vk-internship-application/internal/service/listing"
 	"github.com/justcgh9/vk-internship-application/internal/storage"
+	"github.com/justcgh9/vk-internship-application/pkg/httpclient"
 )
 
 type mockAuthService struct {
@@ -28,13 +31,30 @@ func (m *mockAuthService) GetUser(ctx context.Context, id int64) (*models.User,
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *mockAuthService) Register(ctx context.Context, username, password string) (*models.User, string, error) {
+func (m *mockAuthService) SetRole(ctx context.Context, id int64, role models.Role) (*models.User, error) {
 	panic("not used in this test")
 }
-func (m *mockAuthService) Login(ctx context.Context, username, password string) (string, error) {
+
+func (m *mockAuthService) Register(ctx context.Context, username, password string) (*models.User, *auth.TokenPair, error) {
+	panic("not used in this test")
+}
+func (m *mockAuthService) Login(ctx context.Context, username, password string) (*auth.TokenPair, error) {
+	panic("not used in this test")
+}
+func (m *mockAuthService) Refresh(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
 	panic("not used in this test")
 }
-func (m *mockAuthService) VerifyToken(token string) (int64, error) {
+func (m *mockAuthService) Logout(ctx context.Context, claims *auth.Claims, refreshToken string) error {
+	panic("not used in this test")
+}
+func (m *mockAuthService) LogoutAll(ctx context.Context, userID int64) error {
+	panic("not used in this test")
+}
+func (m *mockAuthService) VerifyToken(ctx context.Context, token string) (*auth.Claims, error) {
+	panic("not used in this test")
+}
+
+func (m *mockAuthService) JWKS() auth.JWKS {
 	panic("not used in this test")
 }
 
@@ -47,9 +67,39 @@ func (m *mockListingService) Create(ctx context.Context, l *models.Listing) (*mo
 	return args.Get(0).(*models.Listing), args.Error(1)
 }
 
-func (m *mockListingService) List(ctx context.Context, f storage.ListFilter) ([]*models.ListingWithAuthor, error) {
+func (m *mockListingService) Get(ctx context.Context, id int64) (*models.Listing, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.Listing), args.Error(1)
+}
+
+func (m *mockListingService) Update(ctx context.Context, userID int64, role models.Role, id int64, upd storage.ListingUpdate) (*models.Listing, error) {
+	args := m.Called(ctx, userID, role, id, upd)
+	return args.Get(0).(*models.Listing), args.Error(1)
+}
+
+func (m *mockListingService) Delete(ctx context.Context, userID int64, role models.Role, id int64) error {
+	args := m.Called(ctx, userID, role, id)
+	return args.Error(0)
+}
+
+func (m *mockListingService) List(ctx context.Context, f storage.ListFilter) (*listing.Page, error) {
 	args := m.Called(ctx, f)
-	return args.Get(0).([]*models.ListingWithAuthor), args.Error(1)
+	if page := args.Get(0); page != nil {
+		return page.(*listing.Page), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// imageServer serves every path with the given content type.
+func imageServer(t *testing.T, contentType string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write([]byte("not really an image"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
 }
 
 func TestCreateListing(t *testing.T) {
@@ -57,14 +107,15 @@ func TestCreateListing(t *testing.T) {
 
 	authSvc := new(mockAuthService)
 	listingSvc := new(mockListingService)
+	images := imageServer(t, "image/jpeg")
 
-	h := listings.New(authSvc, listingSvc, validate)
+	h := listings.New(authSvc, listingSvc, validate, listings.NopMetrics{}, httpclient.New(httpclient.Options{}))
 
 	userID := int64(123)
 	input := listings.CreateListingRequest{
 		Title:       "Test Listing",
 		Description: "A valid description for the listing",
-		ImageURL:    "https://i.pinimg.com/474x/bd/a8/0e/bda80e9324bd6d5c83b84b6eac5a1e5d.jpg",
+		ImageURL:    images.URL + "/bike.jpg",
 		Price:       123.45,
 	}
 	body, _ := json.Marshal(input)
@@ -109,3 +160,26 @@ func TestCreateListing(t *testing.T) {
 	require.Equal(t, "tester", out.AuthorLogin)
 	require.True(t, out.IsOwned)
 }
+
+func TestCreateListing_ImageFormat(t *testing.T) {
+	listingSvc := new(mockListingService)
+	images := imageServer(t, "text/html")
+
+	h := listings.New(new(mockAuthService), listingSvc, validator.New(), listings.NopMetrics{}, httpclient.New(httpclient.Options{}))
+
+	body, _ := json.Marshal(listings.CreateListingRequest{
+		Title:       "Test Listing",
+		Description: "A valid description for the listing",
+		ImageURL:    images.URL + "/page.html",
+		Price:       10,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req = req.WithContext(middleware.WithUserID(context.Background(), 123))
+	w := httptest.NewRecorder()
+
+	h.CreateListing(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "image_format_unsupported")
+	listingSvc.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
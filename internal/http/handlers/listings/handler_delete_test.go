@@ -0,0 +1,78 @@
+package listings_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/handlers/listings"
+	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/service/auth"
+	"github.com/justcgh9/vk-internship-application/internal/service/listing"
+)
+
+func TestDeleteListing_Success(t *testing.T) {
+	listingSvc := new(mockListingService)
+	h := listings.New(new(mockAuthService), listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	listingSvc.On("Delete", mock.Anything, int64(12), models.RoleUser, int64(3)).Return(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/3", nil)
+	req = req.WithContext(withListingID(middleware.WithUserID(context.Background(), 12), "3"))
+	w := httptest.NewRecorder()
+
+	h.DeleteListing(w, req)
+
+	require.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+	listingSvc.AssertExpectations(t)
+}
+
+func TestDeleteListing_NotFound(t *testing.T) {
+	listingSvc := new(mockListingService)
+	h := listings.New(new(mockAuthService), listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	listingSvc.On("Delete", mock.Anything, int64(12), models.RoleUser, int64(3)).Return(listing.ErrListingNotFound)
+
+	req := httptest.NewRequest(http.MethodDelete, "/3", nil)
+	req = req.WithContext(withListingID(middleware.WithUserID(context.Background(), 12), "3"))
+	w := httptest.NewRecorder()
+
+	h.DeleteListing(w, req)
+
+	require.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestDeleteListing_Unauthorized(t *testing.T) {
+	h := listings.New(new(mockAuthService), new(mockListingService), validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	req := httptest.NewRequest(http.MethodDelete, "/3", nil)
+	req = req.WithContext(withListingID(context.Background(), "3"))
+	w := httptest.NewRecorder()
+
+	h.DeleteListing(w, req)
+
+	require.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+}
+
+func TestDeleteListing_AsModerator(t *testing.T) {
+	listingSvc := new(mockListingService)
+	h := listings.New(new(mockAuthService), listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	claims := &auth.Claims{UserID: 99, Role: models.RoleModerator}
+	listingSvc.On("Delete", mock.Anything, int64(99), models.RoleModerator, int64(3)).Return(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/3", nil)
+	req = req.WithContext(withListingID(middleware.WithClaims(context.Background(), claims), "3"))
+	w := httptest.NewRecorder()
+
+	h.DeleteListing(w, req)
+
+	require.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+	listingSvc.AssertExpectations(t)
+}
@@ -0,0 +1,93 @@
+package listings_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/handlers/listings"
+	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/service/listing"
+)
+
+func withListingID(ctx context.Context, id string) context.Context {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	return context.WithValue(ctx, chi.RouteCtxKey, rctx)
+}
+
+func TestGetListing_Success(t *testing.T) {
+	authSvc := new(mockAuthService)
+	listingSvc := new(mockListingService)
+
+	h := listings.New(authSvc, listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	viewerID := int64(5)
+	stored := &models.Listing{
+		ID:          9,
+		Title:       "Bike",
+		Description: "Barely used city bike",
+		ImageURL:    "https://example.com/bike.png",
+		Price:       150,
+		UserID:      viewerID,
+		CreatedAt:   time.Now(),
+	}
+
+	listingSvc.On("Get", mock.Anything, int64(9)).Return(stored, nil)
+	authSvc.On("GetUser", mock.Anything, viewerID).Return(&models.User{ID: viewerID, Username: "rider"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/9", nil)
+	req = req.WithContext(withListingID(middleware.WithUserID(context.Background(), viewerID), "9"))
+	w := httptest.NewRecorder()
+
+	h.GetListing(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var out models.ListingWithAuthor
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	require.Equal(t, stored.ID, out.ID)
+	require.Equal(t, "rider", out.AuthorLogin)
+	require.True(t, out.IsOwned)
+}
+
+func TestGetListing_NotFound(t *testing.T) {
+	authSvc := new(mockAuthService)
+	listingSvc := new(mockListingService)
+
+	h := listings.New(authSvc, listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	listingSvc.On("Get", mock.Anything, int64(9)).Return((*models.Listing)(nil), listing.ErrListingNotFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/9", nil)
+	req = req.WithContext(withListingID(context.Background(), "9"))
+	w := httptest.NewRecorder()
+
+	h.GetListing(w, req)
+
+	require.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestGetListing_InvalidID(t *testing.T) {
+	h := listings.New(new(mockAuthService), new(mockListingService), validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req = req.WithContext(withListingID(context.Background(), "abc"))
+	w := httptest.NewRecorder()
+
+	h.GetListing(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
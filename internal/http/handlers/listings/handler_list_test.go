@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,7 +17,9 @@ import (
 	"github.com/justcgh9/vk-internship-application/internal/http/handlers/listings"
 	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
 	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/service/listing"
 	"github.com/justcgh9/vk-internship-application/internal/storage"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
 )
 
 func TestListListings_Basic(t *testing.T) {
@@ -24,7 +27,7 @@ func TestListListings_Basic(t *testing.T) {
 	authSvc := new(mockAuthService)
 	listingSvc := new(mockListingService)
 
-	h := listings.New(authSvc, listingSvc, validate)
+	h := listings.New(authSvc, listingSvc, validate, listings.NopMetrics{}, http.DefaultClient)
 
 	expected := []*models.ListingWithAuthor{
 		{
@@ -43,7 +46,7 @@ func TestListListings_Basic(t *testing.T) {
 		On("List", mock.Anything, mock.MatchedBy(func(f storage.ListFilter) bool {
 			return f.Limit == 10 && f.Offset == 0 && f.ViewerID == nil
 		})).
-		Return(expected, nil)
+		Return(&listing.Page{Items: expected}, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/?limit=10&offset=0", nil)
 	w := httptest.NewRecorder()
@@ -55,11 +58,16 @@ func TestListListings_Basic(t *testing.T) {
 
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 
-	var listingsOut []*models.ListingWithAuthor
-	err := json.NewDecoder(resp.Body).Decode(&listingsOut)
+	var out listings.ListListingsResponse
+	err := json.NewDecoder(resp.Body).Decode(&out)
 	require.NoError(t, err)
-	require.Len(t, listingsOut, 1)
-	require.Equal(t, expected[0].Title, listingsOut[0].Title)
+	require.Len(t, out.Items, 1)
+	require.Equal(t, expected[0].Title, out.Items[0].Title)
+	require.Equal(t, 10, out.Limit)
+	require.NotNil(t, out.Offset)
+	require.Equal(t, 0, *out.Offset)
+	require.Nil(t, out.Total)
+	require.Empty(t, resp.Header.Get("Link"))
 }
 
 func TestListListings_WithViewer(t *testing.T) {
@@ -67,7 +75,7 @@ func TestListListings_WithViewer(t *testing.T) {
 	authSvc := new(mockAuthService)
 	listingSvc := new(mockListingService)
 
-	h := listings.New(authSvc, listingSvc, validate)
+	h := listings.New(authSvc, listingSvc, validate, listings.NopMetrics{}, http.DefaultClient)
 
 	viewerID := int64(42)
 	expected := []*models.ListingWithAuthor{
@@ -86,7 +94,7 @@ func TestListListings_WithViewer(t *testing.T) {
 		On("List", mock.Anything, mock.MatchedBy(func(f storage.ListFilter) bool {
 			return f.ViewerID != nil && *f.ViewerID == viewerID
 		})).
-		Return(expected, nil)
+		Return(&listing.Page{Items: expected}, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	req = req.WithContext(middleware.WithUserID(context.Background(), viewerID))
@@ -98,11 +106,11 @@ func TestListListings_WithViewer(t *testing.T) {
 	defer resp.Body.Close()
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 
-	var out []*models.ListingWithAuthor
+	var out listings.ListListingsResponse
 	err := json.NewDecoder(resp.Body).Decode(&out)
 	require.NoError(t, err)
-	require.Len(t, out, 1)
-	require.True(t, out[0].IsOwned)
+	require.Len(t, out.Items, 1)
+	require.True(t, out.Items[0].IsOwned)
 }
 
 func TestListListings_Empty(t *testing.T) {
@@ -110,11 +118,11 @@ func TestListListings_Empty(t *testing.T) {
 	authSvc := new(mockAuthService)
 	listingSvc := new(mockListingService)
 
-	h := listings.New(authSvc, listingSvc, validate)
+	h := listings.New(authSvc, listingSvc, validate, listings.NopMetrics{}, http.DefaultClient)
 
 	listingSvc.
 		On("List", mock.Anything, mock.Anything).
-		Return([]*models.ListingWithAuthor{}, nil)
+		Return(&listing.Page{}, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	w := httptest.NewRecorder()
@@ -126,10 +134,12 @@ func TestListListings_Empty(t *testing.T) {
 
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 
-	var out []*models.ListingWithAuthor
+	var out map[string]any
 	err := json.NewDecoder(resp.Body).Decode(&out)
 	require.NoError(t, err)
-	require.Empty(t, out)
+	require.Equal(t, []any{}, out["items"])
+	require.NotContains(t, out, "total")
+	require.NotContains(t, out, "next_cursor")
 }
 
 func TestListListings_ErrorFromService(t *testing.T) {
@@ -137,11 +147,11 @@ func TestListListings_ErrorFromService(t *testing.T) {
 	authSvc := new(mockAuthService)
 	listingSvc := new(mockListingService)
 
-	h := listings.New(authSvc, listingSvc, validate)
+	h := listings.New(authSvc, listingSvc, validate, listings.NopMetrics{}, http.DefaultClient)
 
 	listingSvc.
 		On("List", mock.Anything, mock.Anything).
-		Return([]*models.ListingWithAuthor{}, context.DeadlineExceeded)
+		Return(nil, context.DeadlineExceeded)
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	w := httptest.NewRecorder()
@@ -159,7 +169,7 @@ func TestListListings_WithFilters(t *testing.T) {
 	authSvc := new(mockAuthService)
 	listingSvc := new(mockListingService)
 
-	h := listings.New(authSvc, listingSvc, validate)
+	h := listings.New(authSvc, listingSvc, validate, listings.NopMetrics{}, http.DefaultClient)
 
 	query := url.Values{}
 	query.Set("limit", "5")
@@ -168,6 +178,7 @@ func TestListListings_WithFilters(t *testing.T) {
 	query.Set("price_max", "200")
 	query.Set("sort_by", "price")
 	query.Set("sort_order", "desc")
+	query.Set("q", "  red bike ")
 
 	listingSvc.
 		On("List", mock.Anything, mock.MatchedBy(func(f storage.ListFilter) bool {
@@ -175,10 +186,43 @@ func TestListListings_WithFilters(t *testing.T) {
 				f.Offset == 2 &&
 				f.SortBy == "price" &&
 				f.SortOrder == "desc" &&
+				f.Query == "red bike" &&
 				f.PriceMin != nil && *f.PriceMin == 100 &&
 				f.PriceMax != nil && *f.PriceMax == 200
 		})).
-		Return([]*models.ListingWithAuthor{}, nil)
+		Return(&listing.Page{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil)
+	w := httptest.NewRecorder()
+
+	h.ListListings(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestListListings_WithCursor(t *testing.T) {
+	listingSvc := new(mockListingService)
+	h := listings.New(new(mockAuthService), listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	after := &storage.Cursor{SortBy: storage.SortByPrice, SortOrder: storage.SortAsc, Value: "150", ID: 9}
+	next := &storage.Cursor{SortBy: storage.SortByPrice, SortOrder: storage.SortAsc, Value: "200", ID: 4}
+
+	listingSvc.
+		On("List", mock.Anything, mock.MatchedBy(func(f storage.ListFilter) bool {
+			return f.After != nil && *f.After == *after
+		})).
+		Return(&listing.Page{
+			Items:      []*models.ListingWithAuthor{{ID: 4, Price: 200}},
+			NextCursor: next.Encode(),
+		}, nil)
+
+	query := url.Values{}
+	query.Set("sort_by", "price")
+	query.Set("sort_order", "asc")
+	query.Set("cursor", after.Encode())
 
 	req := httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil)
 	w := httptest.NewRecorder()
@@ -189,4 +233,216 @@ func TestListListings_WithFilters(t *testing.T) {
 	defer resp.Body.Close()
 
 	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var out listings.ListListingsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	require.Equal(t, next.Encode(), out.NextCursor)
+	require.Nil(t, out.Offset)
+
+	nextQuery := url.Values{}
+	nextQuery.Set("sort_by", "price")
+	nextQuery.Set("sort_order", "asc")
+	nextQuery.Set("cursor", next.Encode())
+	require.Equal(t, `</?`+nextQuery.Encode()+`>; rel="next"`, resp.Header.Get("Link"))
+	listingSvc.AssertExpectations(t)
+}
+
+func TestListListings_InvalidCursor(t *testing.T) {
+	listingSvc := new(mockListingService)
+	h := listings.New(new(mockAuthService), listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	req := httptest.NewRequest(http.MethodGet, "/?cursor=garbage", nil)
+	w := httptest.NewRecorder()
+
+	h.ListListings(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	listingSvc.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestListListings_CursorSortMismatch(t *testing.T) {
+	listingSvc := new(mockListingService)
+	h := listings.New(new(mockAuthService), listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	listingSvc.
+		On("List", mock.Anything, mock.Anything).
+		Return(nil, storage.ErrInvalidCursor)
+
+	after := &storage.Cursor{SortBy: storage.SortByPrice, SortOrder: storage.SortAsc, Value: "150", ID: 9}
+	req := httptest.NewRequest(http.MethodGet, "/?cursor="+after.Encode(), nil)
+	w := httptest.NewRecorder()
+
+	h.ListListings(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestListListings_TotalAndLinks(t *testing.T) {
+	listingSvc := new(mockListingService)
+	h := listings.New(new(mockAuthService), listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	total := 45
+	listingSvc.
+		On("List", mock.Anything, mock.MatchedBy(func(f storage.ListFilter) bool {
+			return f.WithTotal && f.Limit == 10 && f.Offset == 20
+		})).
+		Return(&listing.Page{
+			Items:      []*models.ListingWithAuthor{{ID: 1}},
+			NextCursor: "abc",
+			Total:      &total,
+		}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/listings?include_total=true&limit=10&offset=20", nil)
+	w := httptest.NewRecorder()
+
+	h.ListListings(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var out listings.ListListingsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	require.NotNil(t, out.Total)
+	require.Equal(t, 45, *out.Total)
+	require.Equal(t, 20, *out.Offset)
+
+	require.Equal(t, strings.Join([]string{
+		`</listings?cursor=abc&include_total=true&limit=10>; rel="next"`,
+		`</listings?include_total=true&limit=10>; rel="first"`,
+		`</listings?include_total=true&limit=10&offset=10>; rel="prev"`,
+		`</listings?include_total=true&limit=10&offset=40>; rel="last"`,
+	}, ", "), resp.Header.Get("Link"))
+}
+
+func TestListListings_LastLinkBeyondMaxOffset(t *testing.T) {
+	tests := []struct {
+		name  string
+		total int
+		last  string
+	}{
+		{"last page at the cap", listings.MaxOffset + 50, `</listings?include_total=true&limit=100&offset=10000>; rel="last"`},
+		{"last page past the cap", listings.MaxOffset + 150, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listingSvc := new(mockListingService)
+			h := listings.New(new(mockAuthService), listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+			total := tt.total
+			listingSvc.
+				On("List", mock.Anything, mock.Anything).
+				Return(&listing.Page{Items: []*models.ListingWithAuthor{{ID: 1}}, NextCursor: "abc", Total: &total}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/listings?include_total=true&limit=100&offset=9900", nil)
+			w := httptest.NewRecorder()
+
+			h.ListListings(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			links := w.Header().Get("Link")
+			require.Contains(t, links, `rel="next"`)
+			if tt.last == "" {
+				require.NotContains(t, links, `rel="last"`)
+			} else {
+				require.Contains(t, links, tt.last)
+			}
+		})
+	}
+}
+
+func TestListListings_ExtendedFilters(t *testing.T) {
+	listingSvc := new(mockListingService)
+	h := listings.New(new(mockAuthService), listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	viewerID := int64(42)
+	createdAfter := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	createdBefore := time.Date(2025, 2, 1, 12, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+	listingSvc.
+		On("List", mock.Anything, mock.MatchedBy(func(f storage.ListFilter) bool {
+			return f.AuthorID != nil && *f.AuthorID == 5 &&
+				f.AuthorLogin == "bob" &&
+				f.CreatedAfter != nil && f.CreatedAfter.Equal(createdAfter) &&
+				f.CreatedBefore != nil && f.CreatedBefore.Equal(createdBefore) &&
+				f.HasImage != nil && *f.HasImage &&
+				f.OnlyMine && f.ViewerID != nil && *f.ViewerID == viewerID
+		})).
+		Return(&listing.Page{}, nil)
+
+	query := url.Values{}
+	query.Set("author_id", "5")
+	query.Set("author", "bob")
+	query.Set("created_after", "2025-01-01")
+	query.Set("created_before", "2025-02-01T12:30:00+03:00")
+	query.Set("has_image", "true")
+	query.Set("mine", "true")
+
+	req := httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil)
+	req = req.WithContext(middleware.WithUserID(context.Background(), viewerID))
+	w := httptest.NewRecorder()
+
+	h.ListListings(w, req)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	listingSvc.AssertExpectations(t)
+}
+
+func TestListListings_MineRequiresAuth(t *testing.T) {
+	listingSvc := new(mockListingService)
+	h := listings.New(new(mockAuthService), listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	req := httptest.NewRequest(http.MethodGet, "/?mine=true", nil)
+	w := httptest.NewRecorder()
+
+	h.ListListings(w, req)
+
+	require.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	listingSvc.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestListListings_InvalidQuery(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		query  string
+		fields []string
+	}{
+		{"malformed values", "limit=ten&price_min=cheap&has_image=maybe&created_after=yesterday", []string{"limit", "price_min", "has_image", "created_after"}},
+		{"out of range", "limit=0&offset=-1&author_id=0", []string{"limit", "offset", "author_id"}},
+		{"page too large", "limit=101&offset=10001", []string{"limit", "offset"}},
+		{"unknown sort", "sort_by=title&sort_order=up", []string{"sort_by", "sort_order"}},
+		{"inverted price range", "price_min=500&price_max=100", []string{"price_min"}},
+		{"inverted date range", "created_after=2025-02-01&created_before=2025-01-01", []string{"created_after"}},
+		{"cursor with offset", "cursor=garbage&offset=20", []string{"cursor", "offset"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			listingSvc := new(mockListingService)
+			h := listings.New(new(mockAuthService), listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+			req := httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil)
+			w := httptest.NewRecorder()
+
+			h.ListListings(w, req)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+			require.Equal(t, httpx.ProblemContentType, resp.Header.Get("Content-Type"))
+
+			var out httpx.Problem
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+			require.Equal(t, "invalid_query", out.Code)
+
+			var fields []string
+			for _, f := range out.Errors {
+				require.NotEmpty(t, f.Message)
+				fields = append(fields, f.Field)
+			}
+			require.ElementsMatch(t, tc.fields, fields)
+			listingSvc.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+		})
+	}
 }
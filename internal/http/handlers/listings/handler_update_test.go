@@ -0,0 +1,99 @@
+package listings_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/handlers/listings"
+	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/service/listing"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+)
+
+func TestUpdateListing_Success(t *testing.T) {
+	authSvc := new(mockAuthService)
+	listingSvc := new(mockListingService)
+
+	h := listings.New(authSvc, listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	userID := int64(12)
+	price := 75.5
+	body, _ := json.Marshal(map[string]any{"price": price})
+
+	updated := &models.Listing{
+		ID:          3,
+		Title:       "Chair",
+		Description: "Comfortable wooden chair",
+		Price:       price,
+		UserID:      userID,
+		CreatedAt:   time.Now(),
+	}
+
+	listingSvc.
+		On("Update", mock.Anything, userID, models.RoleUser, int64(3), mock.MatchedBy(func(u storage.ListingUpdate) bool {
+			return u.Price != nil && *u.Price == price && u.Title == nil
+		})).
+		Return(updated, nil)
+	authSvc.On("GetUser", mock.Anything, userID).Return(&models.User{ID: userID, Username: "seller"}, nil)
+
+	req := httptest.NewRequest(http.MethodPatch, "/3", bytes.NewReader(body))
+	req = req.WithContext(withListingID(middleware.WithUserID(context.Background(), userID), "3"))
+	w := httptest.NewRecorder()
+
+	h.UpdateListing(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var out models.ListingWithAuthor
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	require.Equal(t, price, out.Price)
+	require.True(t, out.IsOwned)
+}
+
+func TestUpdateListing_Forbidden(t *testing.T) {
+	authSvc := new(mockAuthService)
+	listingSvc := new(mockListingService)
+
+	h := listings.New(authSvc, listingSvc, validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	body, _ := json.Marshal(map[string]any{"title": "Stolen title"})
+
+	listingSvc.
+		On("Update", mock.Anything, int64(12), models.RoleUser, int64(3), mock.Anything).
+		Return((*models.Listing)(nil), listing.ErrForbidden)
+
+	req := httptest.NewRequest(http.MethodPatch, "/3", bytes.NewReader(body))
+	req = req.WithContext(withListingID(middleware.WithUserID(context.Background(), 12), "3"))
+	w := httptest.NewRecorder()
+
+	h.UpdateListing(w, req)
+
+	require.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+}
+
+func TestUpdateListing_ValidationError(t *testing.T) {
+	h := listings.New(new(mockAuthService), new(mockListingService), validator.New(), listings.NopMetrics{}, http.DefaultClient)
+
+	body, _ := json.Marshal(map[string]any{"title": "ab"})
+
+	req := httptest.NewRequest(http.MethodPatch, "/3", bytes.NewReader(body))
+	req = req.WithContext(withListingID(middleware.WithUserID(context.Background(), 12), "3"))
+	w := httptest.NewRecorder()
+
+	h.UpdateListing(w, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+}
@@ -0,0 +1,74 @@
+package listings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+)
+
+const (
+	MaxImageSize = 5 * 1024 * 1024
+)
+
+var (
+	errImageUnreachable = errors.New("invalid image URL")
+	errImageFormat      = errors.New("unsupported image format")
+	errImageTooLarge    = errors.New("image too large")
+)
+
+// checkImage makes sure the URL points to a reasonably sized JPEG or PNG image.
+func (h *Handler) checkImage(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errImageUnreachable, err.Error())
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errImageUnreachable, err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status %d", errImageUnreachable, resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		return fmt.Errorf("%w: %s", errImageFormat, contentType)
+	}
+
+	if size := resp.ContentLength; size > MaxImageSize {
+		return fmt.Errorf("%w: %d bytes", errImageTooLarge, size)
+	}
+
+	return nil
+}
+
+// imageResult classifies a checkImage error for Metrics.
+func imageResult(err error) string {
+	switch {
+	case err == nil:
+		return ImageOK
+	case errors.Is(err, errImageFormat):
+		return ImageBadFormat
+	case errors.Is(err, errImageTooLarge):
+		return ImageTooLarge
+	default:
+		return ImageUnreachable
+	}
+}
+
+// imageProblem returns the client-facing representation of a checkImage error.
+func imageProblem(err error) *httpx.Error {
+	switch {
+	case errors.Is(err, errImageFormat):
+		return httpx.NewError(http.StatusBadRequest, "image_format_unsupported", "image must be a JPEG or PNG").Wrap(err)
+	case errors.Is(err, errImageTooLarge):
+		return httpx.NewError(http.StatusBadRequest, "image_too_large", "image must not exceed 5 MiB").Wrap(err)
+	default:
+		return httpx.NewError(http.StatusBadRequest, "image_unreachable", "image URL could not be fetched").Wrap(err)
+	}
+}
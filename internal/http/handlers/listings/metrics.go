@@ -0,0 +1,21 @@
+package listings
+
+import "time"
+
+// Outcomes of an image URL check, as reported to Metrics.
+const (
+	ImageOK          = "ok"
+	ImageUnreachable = "unreachable"
+	ImageBadFormat   = "bad_format"
+	ImageTooLarge    = "too_large"
+)
+
+// Metrics records the outbound requests the handler makes.
+type Metrics interface {
+	ImageChecked(result string, d time.Duration)
+}
+
+// NopMetrics discards everything.
+type NopMetrics struct{}
+
+func (NopMetrics) ImageChecked(string, time.Duration) {}
@@ -0,0 +1,76 @@
+package listings
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/service/listing"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+)
+
+// ListListingsResponse is the envelope around a page of listings. Offset is
+// only reported in offset mode, Total only when include_total was requested.
+type ListListingsResponse struct {
+	Items      []*models.ListingWithAuthor `json:"items"`
+	Total      *int                        `json:"total,omitempty"`
+	Limit      int                         `json:"limit"`
+	Offset     *int                        `json:"offset,omitempty"`
+	NextCursor string                      `json:"next_cursor,omitempty"`
+}
+
+func newListListingsResponse(filter storage.ListFilter, page *listing.Page) ListListingsResponse {
+	resp := ListListingsResponse{
+		Items:      page.Items,
+		Total:      page.Total,
+		Limit:      filter.Limit,
+		NextCursor: page.NextCursor,
+	}
+	if resp.Items == nil {
+		resp.Items = []*models.ListingWithAuthor{}
+	}
+	if filter.After == nil {
+		offset := filter.Offset
+		resp.Offset = &offset
+	}
+	return resp
+}
+
+// linkHeader renders RFC 8288 navigation links for the page, keeping every
+// other query parameter of the current request. The next page is always
+// addressed by cursor; first, prev and last only make sense in offset mode.
+// The last page is left out when it lies beyond MaxOffset, since the link
+// would be rejected; such pages are reached by following next.
+func linkHeader(u *url.URL, filter storage.ListFilter, page *listing.Page) string {
+	var links []string
+	link := func(rel string, set map[string]string) {
+		q := u.Query()
+		q.Del("cursor")
+		q.Del("offset")
+		for k, v := range set {
+			q.Set(k, v)
+		}
+		links = append(links, fmt.Sprintf(`<%s?%s>; rel="%s"`, u.Path, q.Encode(), rel))
+	}
+
+	if page.NextCursor != "" {
+		link("next", map[string]string{"cursor": page.NextCursor})
+	}
+
+	if filter.After == nil && filter.Limit > 0 {
+		if filter.Offset > 0 {
+			link("first", nil)
+			prev := max(filter.Offset-filter.Limit, 0)
+			link("prev", map[string]string{"offset": strconv.Itoa(prev)})
+		}
+		if page.Total != nil && *page.Total > 0 {
+			if last := (*page.Total - 1) / filter.Limit * filter.Limit; last <= MaxOffset {
+				link("last", map[string]string{"offset": strconv.Itoa(last)})
+			}
+		}
+	}
+
+	return strings.Join(links, ", ")
+}
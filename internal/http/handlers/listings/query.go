@@ -0,0 +1,198 @@
+package listings
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+)
+
+const (
+	DefaultPageSize = 10
+	// MaxOffset is the deepest offset accepted, matching the validate tag
+	// of ListListingsQuery.Offset.
+	MaxOffset = 10000
+)
+
+// ListListingsQuery holds the parsed query parameters of GET /listings. Pages
+// hold at most 100 listings, and offset pagination stops at 10000; deeper
+// pages have to be reached with cursors.
+type ListListingsQuery struct {
+	Limit         int        `query:"limit" validate:"min=1,max=100"`
+	Offset        int        `query:"offset" validate:"min=0,max=10000"`
+	Cursor        string     `query:"cursor"`
+	IncludeTotal  bool       `query:"include_total"`
+	SortBy        string     `query:"sort_by" validate:"omitempty,oneof=created_at price relevance"`
+	SortOrder     string     `query:"sort_order" validate:"omitempty,oneof=asc desc"`
+	Q             string     `query:"q" validate:"max=200"`
+	PriceMin      *float64   `query:"price_min" validate:"omitempty,min=0"`
+	PriceMax      *float64   `query:"price_max" validate:"omitempty,min=0"`
+	AuthorID      *int64     `query:"author_id" validate:"omitempty,min=1"`
+	Author        string     `query:"author" validate:"max=32"`
+	CreatedAfter  *time.Time `query:"created_after"`
+	CreatedBefore *time.Time `query:"created_before"`
+	HasImage      *bool      `query:"has_image"`
+	Mine          bool       `query:"mine"`
+
+	after *storage.Cursor
+}
+
+// parseListQuery parses and validates the query string, collecting every
+// problem instead of stopping at the first one.
+func parseListQuery(values url.Values, v *validator.Validate) (*ListListingsQuery, []httpx.FieldError) {
+	p := queryParser{values: values}
+
+	q := &ListListingsQuery{
+		Limit:         p.int("limit", DefaultPageSize),
+		Offset:        p.int("offset", 0),
+		Cursor:        values.Get("cursor"),
+		IncludeTotal:  p.flag("include_total"),
+		SortBy:        values.Get("sort_by"),
+		SortOrder:     values.Get("sort_order"),
+		Q:             strings.TrimSpace(values.Get("q")),
+		PriceMin:      p.float("price_min"),
+		PriceMax:      p.float("price_max"),
+		AuthorID:      p.int64("author_id"),
+		Author:        values.Get("author"),
+		CreatedAfter:  p.time("created_after"),
+		CreatedBefore: p.time("created_before"),
+		HasImage:      p.bool("has_image"),
+		Mine:          p.flag("mine"),
+	}
+	errs := p.errs
+
+	if err := v.Struct(q); err != nil {
+		errs = append(errs, httpx.FieldErrors(err, q)...)
+	}
+
+	if q.PriceMin != nil && q.PriceMax != nil && *q.PriceMin > *q.PriceMax {
+		errs = append(errs, httpx.FieldError{Field: "price_min", Message: "must not exceed price_max"})
+	}
+	if q.CreatedAfter != nil && q.CreatedBefore != nil && !q.CreatedAfter.Before(*q.CreatedBefore) {
+		errs = append(errs, httpx.FieldError{Field: "created_after", Message: "must be before created_before"})
+	}
+
+	if q.Cursor != "" {
+		after, err := storage.DecodeCursor(q.Cursor)
+		if err != nil {
+			errs = append(errs, httpx.FieldError{Field: "cursor", Message: "is not a valid cursor"})
+		}
+		if values.Has("offset") {
+			errs = append(errs, httpx.FieldError{Field: "offset", Message: "cannot be combined with cursor"})
+		}
+		q.after = after
+	}
+
+	return q, errs
+}
+
+func (q *ListListingsQuery) Filter() storage.ListFilter {
+	return storage.ListFilter{
+		Limit:         q.Limit,
+		Offset:        q.Offset,
+		After:         q.after,
+		WithTotal:     q.IncludeTotal,
+		Query:         q.Q,
+		SortBy:        q.SortBy,
+		SortOrder:     q.SortOrder,
+		PriceMin:      q.PriceMin,
+		PriceMax:      q.PriceMax,
+		AuthorID:      q.AuthorID,
+		AuthorLogin:   q.Author,
+		CreatedAfter:  q.CreatedAfter,
+		CreatedBefore: q.CreatedBefore,
+		HasImage:      q.HasImage,
+		OnlyMine:      q.Mine,
+	}
+}
+
+// queryParser converts raw query values, remembering which ones were
+// malformed. Absent parameters are never an error.
+type queryParser struct {
+	values url.Values
+	errs   []httpx.FieldError
+}
+
+func (p *queryParser) fail(name, message string) {
+	p.errs = append(p.errs, httpx.FieldError{Field: name, Message: message})
+}
+
+func (p *queryParser) int(name string, def int) int {
+	raw := p.values.Get(name)
+	if raw == "" {
+		return def
+	}
+	val, err := strconv.Atoi(raw)
+	if err != nil {
+		p.fail(name, "must be an integer")
+		return def
+	}
+	return val
+}
+
+func (p *queryParser) int64(name string) *int64 {
+	raw := p.values.Get(name)
+	if raw == "" {
+		return nil
+	}
+	val, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		p.fail(name, "must be an integer")
+		return nil
+	}
+	return &val
+}
+
+func (p *queryParser) float(name string) *float64 {
+	raw := p.values.Get(name)
+	if raw == "" {
+		return nil
+	}
+	val, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		p.fail(name, "must be a number")
+		return nil
+	}
+	return &val
+}
+
+func (p *queryParser) bool(name string) *bool {
+	raw := p.values.Get(name)
+	if raw == "" {
+		return nil
+	}
+	val, err := strconv.ParseBool(raw)
+	if err != nil {
+		p.fail(name, "must be true or false")
+		return nil
+	}
+	return &val
+}
+
+func (p *queryParser) flag(name string) bool {
+	val := p.bool(name)
+	return val != nil && *val
+}
+
+// time accepts either a full RFC 3339 timestamp or a bare date, which is
+// taken as midnight UTC.
+func (p *queryParser) time(name string) *time.Time {
+	raw := p.values.Get(name)
+	if raw == "" {
+		return nil
+	}
+	if val, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &val
+	}
+	val, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		p.fail(name, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		return nil
+	}
+	return &val
+}
@@ -0,0 +1,103 @@
+package listings
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
+	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+type UpdateListingRequest struct {
+	Title       *string  `json:"title" validate:"omitempty,min=3,max=100"`
+	Description *string  `json:"description" validate:"omitempty,min=10,max=500"`
+	ImageURL    *string  `json:"image_url" validate:"omitempty,url"`
+	Price       *float64 `json:"price" validate:"omitempty,gt=0"`
+}
+
+func (h *Handler) UpdateListing(w http.ResponseWriter, r *http.Request) {
+	ctx, span := otel.Tracer("vk-intern-app").Start(r.Context(), "listings.update")
+	defer span.End()
+
+	log := logger.
+		FromContext(ctx).
+		With("component", "handler").
+		With("function", "update_listing")
+
+	log.Info("update listing request received", slog.String("method", r.Method), slog.String("url", r.RequestURI))
+
+	id, err := parseListingID(r)
+	if err != nil {
+		log.Warn("invalid listing id", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid listing id")
+		apierror.Write(w, r, errInvalidListingID.Wrap(err))
+		return
+	}
+
+	var req UpdateListingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("error decoding request body", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid JSON")
+		apierror.Write(w, r, httpx.ErrInvalidJSON.Wrap(err))
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		log.Error("validation failed", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "validation failed")
+		apierror.Write(w, r, httpx.ErrValidation.WithFields(httpx.FieldErrors(err, req)...))
+		return
+	}
+
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		log.Warn("unauthorized request - no user ID in context")
+		span.SetStatus(codes.Error, "unauthorized")
+		apierror.Write(w, r, httpx.ErrUnauthorized)
+		return
+	}
+	span.SetAttributes(
+		attribute.Int64("user.id", userID),
+		attribute.Int64("listing.id", id),
+	)
+
+	if req.ImageURL != nil {
+		if err := h.checkImage(ctx, *req.ImageURL); err != nil {
+			log.Warn("image validation failed", slog.String("err", err.Error()))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "image validation failed")
+			apierror.Write(w, r, imageProblem(err))
+			return
+		}
+	}
+
+	updated, err := h.listingSvc.Update(ctx, userID, middleware.GetRole(ctx), id, storage.ListingUpdate{
+		Title:       req.Title,
+		Description: req.Description,
+		ImageURL:    req.ImageURL,
+		Price:       req.Price,
+	})
+	if err != nil {
+		log.Warn("failed to update listing", slog.String("err", err.Error()))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "listing update failed")
+		apierror.Write(w, r, err)
+		return
+	}
+
+	log.Info("listing updated", slog.Int64("listing_id", id), slog.Int64("user_id", userID))
+	span.SetStatus(codes.Ok, "listing updated")
+
+	httpx.WriteJSON(w, http.StatusOK, h.withAuthor(ctx, updated, &userID))
+}
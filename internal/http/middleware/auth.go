@@ -6,16 +6,21 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
 	"github.com/justcgh9/vk-internship-application/internal/service/auth"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
 	"github.com/justcgh9/vk-internship-application/pkg/logger"
 )
 
 type contextKey string
 
 const userIDKey contextKey = "user_id"
+const claimsKey contextKey = "claims"
 const authHeader = "Authorization"
 const bearerPrefix = "Bearer "
 
+var errMalformedHeader = httpx.NewError(http.StatusUnauthorized, "invalid_authorization_header", "Authorization header must use the Bearer scheme")
+
 func AuthMiddleware(authSvc auth.AuthService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -27,21 +32,21 @@ func AuthMiddleware(authSvc auth.AuthService) func(http.Handler) http.Handler {
 			authHeaderVal := r.Header.Get(authHeader)
 			if authHeaderVal == "" || !strings.HasPrefix(authHeaderVal, bearerPrefix) {
 				log.Warn("missing or malformed Authorization header")
-				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				apierror.Write(w, r, httpx.ErrUnauthorized)
 				return
 			}
 
 			token := strings.TrimPrefix(authHeaderVal, bearerPrefix)
-			userID, err := authSvc.VerifyToken(token)
+			claims, err := authSvc.VerifyToken(r.Context(), token)
 			if err != nil {
 				log.Warn("token verification failed", slog.String("err", err.Error()))
-				http.Error(w, "invalid token", http.StatusUnauthorized)
+				apierror.Write(w, r, err)
 				return
 			}
 
-			log.Info("user authenticated", slog.Int64("user_id", userID))
-			ctx := context.WithValue(r.Context(), userIDKey, userID)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			log.Info("user authenticated", slog.Int64("user_id", claims.UserID))
+			logger.With(r.Context(), slog.Int64("user_id", claims.UserID))
+			next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
 		})
 	}
 }
@@ -58,20 +63,21 @@ func OptionalAuthMiddleware(authSvc auth.AuthService) func(http.Handler) http.Ha
 			if authHeaderVal != "" {
 				if !strings.HasPrefix(authHeaderVal, bearerPrefix) {
 					log.Warn("malformed Authorization header")
-					http.Error(w, "invalid authorization header format", http.StatusUnauthorized)
+					apierror.Write(w, r, errMalformedHeader)
 					return
 				}
 
 				token := strings.TrimPrefix(authHeaderVal, bearerPrefix)
-				userID, err := authSvc.VerifyToken(token)
+				claims, err := authSvc.VerifyToken(r.Context(), token)
 				if err != nil {
 					log.Warn("invalid token in optional auth", slog.String("err", err.Error()))
-					http.Error(w, "invalid token", http.StatusUnauthorized)
+					apierror.Write(w, r, err)
 					return
 				}
 
-				log.Info("optional auth: user authenticated", slog.Int64("user_id", userID))
-				r = r.WithContext(context.WithValue(r.Context(), userIDKey, userID))
+				log.Info("optional auth: user authenticated", slog.Int64("user_id", claims.UserID))
+				logger.With(r.Context(), slog.Int64("user_id", claims.UserID))
+				r = r.WithContext(WithClaims(r.Context(), claims))
 			} else {
 				log.Debug("no auth header, continuing unauthenticated")
 			}
@@ -89,3 +95,14 @@ func GetUserID(ctx context.Context) (int64, bool) {
 func WithUserID(ctx context.Context, uid int64) context.Context {
 	return context.WithValue(ctx, userIDKey, uid)
 }
+
+// GetClaims returns the verified access token claims of the current request.
+func GetClaims(ctx context.Context) (*auth.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey).(*auth.Claims)
+	return claims, ok
+}
+
+func WithClaims(ctx context.Context, claims *auth.Claims) context.Context {
+	ctx = context.WithValue(ctx, claimsKey, claims)
+	return context.WithValue(ctx, userIDKey, claims.UserID)
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDKey contextKey = "request_id"
+
+// maxRequestIDLength bounds the IDs accepted from clients, which end up in
+// every log line of the request.
+const maxRequestIDLength = 128
+
+// RequestLogger gives every request an ID, taken from X-Request-ID when the
+// client sent a usable one, and echoes it in the response. Loggers obtained
+// from the request context carry the ID and the client IP; AuthMiddleware
+// adds the user. Once the request is served, one access log line records its
+// outcome and the route pattern.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		ctx = logger.NewContext(ctx,
+			slog.String("request_id", id),
+			slog.String("client_ip", clientIP(r)),
+		)
+		r = r.WithContext(ctx)
+
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK // nothing was written
+			}
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.FromContext(ctx).LogAttrs(ctx, level, "request served",
+				slog.String("component", "access"),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Any("route", routePattern{chi.RouteContext(ctx)}),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Duration("latency", time.Since(start)),
+				slog.String("user_agent", r.UserAgent()),
+			)
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// GetRequestID returns the ID RequestLogger gave the current request.
+func GetRequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range []byte(id) {
+		if c < '!' || c > '~' { // printable ASCII without spaces
+			return false
+		}
+	}
+	return true
+}
+
+// clientIP is the address of the peer. X-Forwarded-For is not consulted, as
+// nothing guarantees it was set by a trusted proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// routePattern logs the pattern of the matched route, such as
+// /listings/{id}. It is resolved when the access log line is written, since
+// routing is not done yet when the request scope starts.
+type routePattern struct {
+	rctx *chi.Context
+}
+
+func (p routePattern) LogValue() slog.Value {
+	if p.rctx == nil {
+		return slog.StringValue("")
+	}
+	return slog.StringValue(p.rctx.RoutePattern())
+}
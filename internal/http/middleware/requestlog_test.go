@@ -0,0 +1,116 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/middleware"
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+// captureLogs points the global logger at a buffer for the duration of the
+// test and returns the decoded lines.
+func captureLogs(t *testing.T) func() []map[string]any {
+	t.Helper()
+
+	var buf bytes.Buffer
+	prev := logger.Log
+	logger.Log = slog.New(slog.NewJSONHandler(&buf, nil))
+	t.Cleanup(func() { logger.Log = prev })
+
+	return func() []map[string]any {
+		var lines []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var m map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &m))
+			lines = append(lines, m)
+		}
+		return lines
+	}
+}
+
+func newRouter() chi.Router {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestLogger)
+	r.Get("/listings/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logger.With(r.Context(), "user_id", 7)
+		logger.FromContext(r.Context()).Info("handling")
+		_, _ = w.Write([]byte("hello"))
+	})
+	return r
+}
+
+func TestRequestLogger_AccessLog(t *testing.T) {
+	logs := captureLogs(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/listings/42", nil)
+	req.RemoteAddr = "192.0.2.1:54321"
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, req)
+
+	id := rr.Header().Get(middleware.RequestIDHeader)
+	require.NotEmpty(t, id)
+
+	lines := logs()
+	require.Len(t, lines, 2)
+
+	handling, access := lines[0], lines[1]
+	assert.Equal(t, id, handling["request_id"])
+	assert.Equal(t, "192.0.2.1", handling["client_ip"])
+
+	assert.Equal(t, "request served", access["msg"])
+	assert.Equal(t, id, access["request_id"])
+	assert.Equal(t, "/listings/{id}", access["route"])
+	assert.Equal(t, "/listings/42", access["path"])
+	assert.EqualValues(t, 7, access["user_id"], "attributes added by inner handlers reach the access log")
+	assert.EqualValues(t, http.StatusOK, access["status"])
+	assert.EqualValues(t, 5, access["bytes"])
+	assert.Contains(t, access, "latency")
+}
+
+func TestRequestLogger_KeepsClientRequestID(t *testing.T) {
+	logs := captureLogs(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/listings/42", nil)
+	req.Header.Set(middleware.RequestIDHeader, "abc-123")
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, "abc-123", rr.Header().Get(middleware.RequestIDHeader))
+	assert.Equal(t, "abc-123", logs()[1]["request_id"])
+}
+
+func TestRequestLogger_ReplacesInvalidRequestID(t *testing.T) {
+	captureLogs(t)
+
+	for _, id := range []string{"has spaces", "line\nbreak", strings.Repeat("x", 200)} {
+		req := httptest.NewRequest(http.MethodGet, "/listings/42", nil)
+		req.Header.Set(middleware.RequestIDHeader, id)
+		rr := httptest.NewRecorder()
+		newRouter().ServeHTTP(rr, req)
+
+		got := rr.Header().Get(middleware.RequestIDHeader)
+		assert.NotEmpty(t, got)
+		assert.NotEqual(t, id, got)
+	}
+}
+
+func TestRequestLogger_NotFound(t *testing.T) {
+	logs := captureLogs(t)
+
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	lines := logs()
+	require.Len(t, lines, 1)
+	assert.EqualValues(t, http.StatusNotFound, lines[0]["status"])
+}
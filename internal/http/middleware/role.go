@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/justcgh9/vk-internship-application/internal/http/apierror"
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/pkg/httpx"
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+// RequireRole lets the request through only if the authenticated user has one
+// of the given roles. It must be installed after AuthMiddleware.
+func RequireRole(roles ...models.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log := logger.
+				FromContext(r.Context()).
+				With("component", "middleware").
+				With("function", "require_role")
+
+			claims, ok := GetClaims(r.Context())
+			if !ok {
+				log.Warn("no claims in context")
+				apierror.Write(w, r, httpx.ErrUnauthorized)
+				return
+			}
+
+			if !slices.Contains(roles, claims.Role) {
+				log.Warn("insufficient role",
+					slog.Int64("user_id", claims.UserID),
+					slog.String("role", string(claims.Role)),
+				)
+				apierror.Write(w, r, httpx.ErrForbidden.WithDetail("insufficient role"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetRole returns the role of the authenticated user, defaulting to a regular user.
+func GetRole(ctx context.Context) models.Role {
+	if claims, ok := GetClaims(ctx); ok && claims.Role != "" {
+		return claims.Role
+	}
+	return models.RoleUser
+}
@@ -0,0 +1,69 @@
+// Package metrics exports what happens in the marketplace: sign-ups, logins,
+// new listings and the image checks done on their behalf. It implements the
+// Metrics interfaces of the services and handlers on top of Prometheus.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Marketplace struct {
+	registrations   prometheus.Counter
+	logins          *prometheus.CounterVec
+	listingsCreated prometheus.Counter
+	listingPrice    prometheus.Histogram
+	imageChecks     *prometheus.HistogramVec
+}
+
+func New(reg prometheus.Registerer) *Marketplace {
+	m := &Marketplace{
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "marketplace_registrations_total",
+			Help: "Users registered",
+		}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "marketplace_logins_total",
+			Help: "Login attempts by result and, for failures, reason",
+		}, []string{"result", "reason"}),
+		listingsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "marketplace_listings_created_total",
+			Help: "Listings created",
+		}),
+		listingPrice: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "marketplace_listing_price",
+			Help:    "Prices of created listings",
+			Buckets: prometheus.ExponentialBuckets(10, 10, 7), // 10 to 10M
+		}),
+		imageChecks: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "marketplace_image_check_duration_seconds",
+			Help:    "Duration of the requests validating listing image URLs",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, []string{"result"}),
+	}
+
+	reg.MustRegister(m.registrations, m.logins, m.listingsCreated, m.listingPrice, m.imageChecks)
+	return m
+}
+
+func (m *Marketplace) UserRegistered() {
+	m.registrations.Inc()
+}
+
+func (m *Marketplace) LoginSucceeded() {
+	m.logins.WithLabelValues("success", "").Inc()
+}
+
+func (m *Marketplace) LoginFailed(reason string) {
+	m.logins.WithLabelValues("failure", reason).Inc()
+}
+
+func (m *Marketplace) ListingCreated(price float64) {
+	m.listingsCreated.Inc()
+	m.listingPrice.Observe(price)
+}
+
+func (m *Marketplace) ImageChecked(result string, d time.Duration) {
+	m.imageChecks.WithLabelValues(result).Observe(d.Seconds())
+}
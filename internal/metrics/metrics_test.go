@@ -0,0 +1,45 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/internal/metrics"
+	"github.com/justcgh9/vk-internship-application/internal/service/auth"
+)
+
+func TestMarketplace(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.New(reg)
+
+	m.UserRegistered()
+	m.LoginSucceeded()
+	m.LoginFailed(auth.LoginWrongPassword)
+	m.LoginFailed(auth.LoginWrongPassword)
+	m.ListingCreated(150)
+	m.ImageChecked("ok", 30*time.Millisecond)
+
+	expected := `
+# HELP marketplace_registrations_total Users registered
+# TYPE marketplace_registrations_total counter
+marketplace_registrations_total 1
+# HELP marketplace_logins_total Login attempts by result and, for failures, reason
+# TYPE marketplace_logins_total counter
+marketplace_logins_total{reason="",result="success"} 1
+marketplace_logins_total{reason="wrong_password",result="failure"} 2
+# HELP marketplace_listings_created_total Listings created
+# TYPE marketplace_listings_created_total counter
+marketplace_listings_created_total 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"marketplace_registrations_total", "marketplace_logins_total", "marketplace_listings_created_total"))
+
+	count, err := testutil.GatherAndCount(reg, "marketplace_listing_price", "marketplace_image_check_duration_seconds")
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+}
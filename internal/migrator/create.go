@@ -0,0 +1,50 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Create adds an empty pair of up and down files for the next version to
+// dir and returns their paths.
+func Create(dir, name string) (up, down string, err error) {
+	if !namePattern.MatchString(name) {
+		return "", "", fmt.Errorf("migration name %q must be snake_case", name)
+	}
+
+	src, err := Source(dir)
+	if err != nil {
+		return "", "", err
+	}
+	schema, err := List(src)
+	src.Close()
+	if err != nil {
+		return "", "", err
+	}
+
+	var next uint = 1
+	if len(schema) > 0 {
+		next = schema[len(schema)-1].Version + 1
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%04d_%s", next, name))
+	up, down = base+".up.sql", base+".down.sql"
+	for _, path := range []string{up, down} {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			if path == down {
+				err = errors.Join(err, os.Remove(up))
+			}
+			return "", "", err
+		}
+		if err := f.Close(); err != nil {
+			return "", "", err
+		}
+	}
+	return up, down, nil
+}
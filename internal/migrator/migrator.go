@@ -0,0 +1,305 @@
+// Package migrator applies the schema migrations and reports on their state.
+// It wraps golang-migrate and adds what the library lacks: a status listing
+// and a plan of the SQL a command would run.
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+
+	"github.com/justcgh9/vk-internship-application/migrations"
+)
+
+type Direction string
+
+const (
+	Up   Direction = "up"
+	Down Direction = "down"
+)
+
+// Migration is one version of the schema.
+type Migration struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// Step is a migration that a command would apply or revert.
+type Step struct {
+	Version   uint
+	Name      string
+	Direction Direction
+	SQL       string
+}
+
+// Version is the state of a database. A database that has never been
+// migrated has no version.
+type Version struct {
+	Version uint
+	None    bool
+	Dirty   bool
+}
+
+func (v Version) String() string {
+	switch {
+	case v.None:
+		return "none"
+	case v.Dirty:
+		return fmt.Sprintf("%d (dirty)", v.Version)
+	}
+	return fmt.Sprint(v.Version)
+}
+
+// Source returns the migrations embedded into the binary, or the ones in dir
+// when it is not empty.
+func Source(dir string) (source.Driver, error) {
+	if dir != "" {
+		return iofs.New(os.DirFS(dir), ".")
+	}
+	return iofs.New(migrations.FS, ".")
+}
+
+type Migrator struct {
+	m      *migrate.Migrate
+	src    source.Driver
+	schema []Migration
+}
+
+// New connects to the database in dbURL. It takes ownership of src.
+func New(src source.Driver, dbURL string) (*Migrator, error) {
+	schema, err := List(src)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, dbURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{m: m, src: src, schema: schema}, nil
+}
+
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+func (m *Migrator) Version() (Version, error) {
+	v, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return Version{None: true}, nil
+	}
+	if err != nil {
+		return Version{}, err
+	}
+	return Version{Version: v, Dirty: dirty}, nil
+}
+
+// Latest is the version the migrations lead up to.
+func (m *Migrator) Latest() uint {
+	if len(m.schema) == 0 {
+		return 0
+	}
+	return m.schema[len(m.schema)-1].Version
+}
+
+// Status lists every known migration and whether the database has it.
+func (m *Migrator) Status() ([]Migration, error) {
+	current, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]Migration, len(m.schema))
+	for i, mig := range m.schema {
+		mig.Applied = !current.None && mig.Version <= current.Version
+		status[i] = mig
+	}
+	return status, nil
+}
+
+// Plan returns the steps from the current version to target, in the order
+// they would run. A target with None set reverts every migration.
+func (m *Migrator) Plan(target Version) ([]Step, error) {
+	current, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if current.Dirty {
+		return nil, fmt.Errorf("database is dirty at version %d, fix it and run force first", current.Version)
+	}
+	return plan(m.src, m.schema, current, target)
+}
+
+// StepsTarget resolves moving n migrations up, or down when n is negative,
+// to the version it leads to.
+func (m *Migrator) StepsTarget(n int) (Version, error) {
+	current, err := m.Version()
+	if err != nil {
+		return Version{}, err
+	}
+
+	i := -1 // index of the current version in the schema
+	for j, mig := range m.schema {
+		if !current.None && mig.Version == current.Version {
+			i = j
+		}
+	}
+	if !current.None && i < 0 {
+		return Version{}, fmt.Errorf("version %d is not among the migrations", current.Version)
+	}
+
+	switch j := i + n; {
+	case j < -1 || j >= len(m.schema):
+		return Version{}, fmt.Errorf("cannot move %+d steps from version %s", n, current)
+	case j == -1:
+		return Version{None: true}, nil
+	default:
+		return Version{Version: m.schema[j].Version}, nil
+	}
+}
+
+// Migrate moves the database to target.
+func (m *Migrator) Migrate(target Version) error {
+	var err error
+	if target.None {
+		err = m.m.Down()
+	} else {
+		err = m.m.Migrate(target.Version)
+	}
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Force sets the version without running anything, typically to clear the
+// dirty flag after fixing a failed migration by hand. -1 means no version.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+// List reads every migration in src, oldest first.
+func List(src source.Driver) ([]Migration, error) {
+	var schema []Migration
+
+	v, err := src.First()
+	for err == nil {
+		name, nameErr := identifier(src, v)
+		if nameErr != nil {
+			return nil, nameErr
+		}
+		schema = append(schema, Migration{Version: v, Name: name})
+		v, err = src.Next(v)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func identifier(src source.Driver, version uint) (string, error) {
+	r, name, err := src.ReadUp(version)
+	if errors.Is(err, fs.ErrNotExist) {
+		r, name, err = src.ReadDown(version)
+	}
+	if err != nil {
+		return "", err
+	}
+	return name, r.Close()
+}
+
+// Plan returns the steps that lead from current to target with the
+// migrations in src, without touching a database.
+func Plan(src source.Driver, current, target Version) ([]Step, error) {
+	schema, err := List(src)
+	if err != nil {
+		return nil, err
+	}
+	return plan(src, schema, current, target)
+}
+
+func plan(src source.Driver, schema []Migration, current, target Version) ([]Step, error) {
+	if !target.None && !known(schema, target.Version) {
+		return nil, fmt.Errorf("version %d is not among the migrations", target.Version)
+	}
+
+	above := func(v uint, bound Version) bool { return !bound.None && v <= bound.Version }
+
+	var steps []Step
+	// Upwards: everything above current up to and including target.
+	for _, mig := range schema {
+		if !above(mig.Version, current) && above(mig.Version, target) {
+			steps = append(steps, Step{Version: mig.Version, Name: mig.Name, Direction: Up})
+		}
+	}
+	// Downwards: everything above target up to and including current, newest first.
+	for i := len(schema) - 1; i >= 0; i-- {
+		mig := schema[i]
+		if above(mig.Version, current) && !above(mig.Version, target) {
+			steps = append(steps, Step{Version: mig.Version, Name: mig.Name, Direction: Down})
+		}
+	}
+
+	for i := range steps {
+		read := src.ReadUp
+		if steps[i].Direction == Down {
+			read = src.ReadDown
+		}
+		r, _, err := read(steps[i].Version)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // golang-migrate treats a missing file as an empty migration
+		}
+		if err != nil {
+			return nil, err
+		}
+		sql, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		steps[i].SQL = string(sql)
+	}
+	return steps, nil
+}
+
+func known(schema []Migration, version uint) bool {
+	for _, mig := range schema {
+		if mig.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLocal reports whether dbURL points to this machine, where running
+// destructive commands needs no confirmation.
+func IsLocal(dbURL string) bool {
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		return false
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		// Unix socket, possibly given as ?host=/var/run/postgresql
+		host = u.Query().Get("host")
+	}
+	switch {
+	case host == "", strings.HasPrefix(host, "/"):
+		return true
+	case host == "localhost", host == "127.0.0.1", host == "::1":
+		return true
+	}
+	return false
+}
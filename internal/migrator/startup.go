@@ -0,0 +1,103 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrSchemaBehind means the database lacks migrations this binary relies on.
+var ErrSchemaBehind = errors.New("database schema is behind the migrations")
+
+// lockKey identifies the advisory lock held while migrating at startup. Any
+// constant works as long as nothing else in the database uses it.
+const lockKey int64 = 0x6d6967726174 // "migrat"
+
+// Conn is a single database session, such as *pgxpool.Conn. Advisory locks
+// belong to a session, so the lock has to be taken and released on the same
+// one.
+type Conn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// WithLock runs fn while holding the startup advisory lock on conn, waiting
+// for it as long as ctx allows. Replicas starting at the same time take
+// turns, and those coming after the first find nothing left to apply.
+func WithLock(ctx context.Context, conn Conn, fn func() error) (err error) {
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		// The context may be done by now, which must not keep the lock held.
+		if _, unlockErr := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockKey); unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("release migration lock: %w", unlockErr))
+		}
+	}()
+
+	return fn()
+}
+
+// Up applies the pending migrations. Unlike Migrate it never reverts
+// anything: a database a newer release has already taken further is left as
+// it is.
+func (m *Migrator) Up() error {
+	current, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if !current.None && !current.Dirty && current.Version >= m.Latest() {
+		return nil
+	}
+
+	if err := m.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Check reports whether the database has every migration applied. A database
+// ahead of the migrations passes, so that an older release keeps running
+// while a newer one rolls out.
+func (m *Migrator) Check() error {
+	current, err := m.Version()
+	if err != nil {
+		return err
+	}
+	return check(current, m.Latest())
+}
+
+// Querier runs a query, like *pgxpool.Pool does.
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// CheckVersion is Check for a running app: it reads the version through the
+// app's own pool rather than opening a connection of its own.
+func CheckVersion(ctx context.Context, db Querier, latest uint) error {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := db.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return check(Version{None: true}, latest)
+	case err != nil:
+		return err
+	}
+	return check(Version{Version: uint(version), Dirty: dirty}, latest)
+}
+
+func check(current Version, latest uint) error {
+	switch {
+	case current.Dirty:
+		return fmt.Errorf("database is dirty at version %d, a migration failed half way", current.Version)
+	case current.Version < latest: // a database without a version is at 0
+		return fmt.Errorf("%w: at version %s, expected %d", ErrSchemaBehind, current, latest)
+	}
+	return nil
+}
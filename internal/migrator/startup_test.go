@@ -0,0 +1,206 @@
+package migrator_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/stub"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/internal/migrator"
+	"github.com/justcgh9/vk-internship-application/internal/storage/postgres/pgtest"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+func TestWithLock(t *testing.T) {
+	mockConn, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mockConn.Close(context.Background())
+
+	mockConn.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mockConn.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+	called := false
+	err = migrator.WithLock(context.Background(), mockConn, func() error {
+		called = true
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, called)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestWithLock_ReleasesOnError(t *testing.T) {
+	mockConn, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mockConn.Close(context.Background())
+
+	errMigrate := errors.New("migration failed")
+
+	mockConn.ExpectExec(`SELECT pg_advisory_lock`).WithArgs(pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mockConn.ExpectExec(`SELECT pg_advisory_unlock`).WithArgs(pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+	err = migrator.WithLock(context.Background(), mockConn, func() error { return errMigrate })
+	assert.ErrorIs(t, err, errMigrate)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestWithLock_LockFailure(t *testing.T) {
+	mockConn, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mockConn.Close(context.Background())
+
+	mockConn.ExpectExec(`SELECT pg_advisory_lock`).WithArgs(pgxmock.AnyArg()).
+		WillReturnError(context.DeadlineExceeded)
+
+	err = migrator.WithLock(context.Background(), mockConn, func() error {
+		t.Fatal("fn must not run without the lock")
+		return nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestCheckAndUp(t *testing.T) {
+	// Reverting a migration would break the tests sharing the pgtest pool.
+	dbURL := pgtest.Database(t)
+
+	src, err := migrator.Source("")
+	require.NoError(t, err)
+	m, err := migrator.New(src, dbURL)
+	require.NoError(t, err)
+	defer m.Close()
+
+	assert.ErrorIs(t, m.Check(), migrator.ErrSchemaBehind)
+	require.NoError(t, m.Up())
+	require.NoError(t, m.Check())
+
+	steps, err := m.StepsTarget(-1)
+	require.NoError(t, err)
+	require.NoError(t, m.Migrate(steps))
+	assert.ErrorIs(t, m.Check(), migrator.ErrSchemaBehind)
+
+	require.NoError(t, m.Up())
+	assert.NoError(t, m.Check())
+	v, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, m.Latest(), v.Version)
+}
+
+func init() { database.Register("dirty-stub", &dirtyStub{}) }
+
+// dirtyStub is the stub driver of a database where migration 2 failed half
+// way.
+type dirtyStub struct{ stub.Stub }
+
+func (*dirtyStub) Open(url string) (database.Driver, error) {
+	return &stub.Stub{Url: url, CurrentVersion: 2, IsDirty: true, Config: &stub.Config{}}, nil
+}
+
+// TestCheck_WithoutDatabase covers the auto_migrate: false startup against
+// golang-migrate's in-memory stub driver.
+func TestCheck_WithoutDatabase(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  migrator.Version
+		wantErr error
+	}{
+		{"up to date", migrator.Version{Version: 4}, nil},
+		{"behind", migrator.Version{Version: 2}, migrator.ErrSchemaBehind},
+		{"never migrated", migrator.Version{None: true}, migrator.ErrSchemaBehind},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := migrator.New(testSource(t), "stub://")
+			require.NoError(t, err)
+			defer m.Close()
+
+			require.NoError(t, m.Migrate(tt.target))
+			err = m.Check()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUp_WithoutDatabase(t *testing.T) {
+	m, err := migrator.New(testSource(t), "stub://")
+	require.NoError(t, err)
+	defer m.Close()
+
+	require.NoError(t, m.Migrate(migrator.Version{Version: 2}))
+	require.NoError(t, m.Up())
+	require.NoError(t, m.Check())
+
+	// A newer release has taken the database further; Up leaves it there.
+	require.NoError(t, m.Force(7))
+	require.NoError(t, m.Up())
+	v, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Version{Version: 7}, v)
+}
+
+func TestCheck_Dirty(t *testing.T) {
+	m, err := migrator.New(testSource(t), "dirty-stub://")
+	require.NoError(t, err)
+	defer m.Close()
+
+	err = m.Check()
+	assert.ErrorContains(t, err, "dirty")
+	assert.NotErrorIs(t, err, migrator.ErrSchemaBehind)
+}
+
+func TestCheckVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    *pgxmock.Rows
+		wantErr error
+	}{
+		{"current", pgxmock.NewRows([]string{"version", "dirty"}).AddRow(int64(5), false), nil},
+		{"ahead", pgxmock.NewRows([]string{"version", "dirty"}).AddRow(int64(6), false), nil},
+		{"behind", pgxmock.NewRows([]string{"version", "dirty"}).AddRow(int64(4), false), migrator.ErrSchemaBehind},
+		{"never migrated", pgxmock.NewRows([]string{"version", "dirty"}), migrator.ErrSchemaBehind},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockConn, err := pgxmock.NewConn()
+			require.NoError(t, err)
+			defer mockConn.Close(context.Background())
+
+			mockConn.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).WillReturnRows(tt.rows)
+
+			err = migrator.CheckVersion(context.Background(), mockConn, 5)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckVersion_Dirty(t *testing.T) {
+	mockConn, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mockConn.Close(context.Background())
+
+	mockConn.ExpectQuery(`SELECT version, dirty FROM schema_migrations`).
+		WillReturnRows(pgxmock.NewRows([]string{"version", "dirty"}).AddRow(int64(5), true))
+
+	err = migrator.CheckVersion(context.Background(), mockConn, 5)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, migrator.ErrSchemaBehind)
+}
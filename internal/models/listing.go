@@ -21,4 +21,9 @@ type ListingWithAuthor struct {
 	AuthorLogin string    `json:"author_login,omitempty"`
 	IsOwned     bool      `json:"is_owned,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	// Set only for full-text searches. Highlight is HTML-escaped with the
+	// matched terms wrapped in <mark> tags.
+	Relevance float32 `json:"relevance,omitempty"`
+	Highlight string  `json:"highlight,omitempty"`
 }
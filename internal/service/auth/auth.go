@@ -5,7 +5,9 @@ import (
 	"errors"
 	"log/slog"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/justcgh9/vk-internship-application/internal/models"
@@ -16,83 +18,323 @@ import (
 var (
 	ErrInvalidCredentials = errors.New("invalid username or password")
 	ErrInvalidInput       = errors.New("invalid input format")
+	ErrUsernameTaken      = errors.New("username is already taken")
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+
+	ErrUserNotFound = errors.New("user not found")
+	ErrInvalidRole  = errors.New("invalid role")
 )
 
+// TokenPair is a short-lived access token and the long-lived refresh token
+// that can be exchanged for the next pair.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
 type AuthService interface {
-	Register(ctx context.Context, username, password string) (*models.User, string, error)
-	Login(ctx context.Context, username, password string) (token string, err error)
-	VerifyToken(token string) (int64, error)
+	Register(ctx context.Context, username, password string) (*models.User, *TokenPair, error)
+	Login(ctx context.Context, username, password string) (*TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	Logout(ctx context.Context, claims *Claims, refreshToken string) error
+	LogoutAll(ctx context.Context, userID int64) error
+	VerifyToken(ctx context.Context, token string) (*Claims, error)
+	JWKS() JWKS
 	GetUser(ctx context.Context, id int64) (*models.User, error)
+	SetRole(ctx context.Context, id int64, role models.Role) (*models.User, error)
 }
 
 type service struct {
+	uow          storage.UnitOfWork
 	userRepo     storage.UserRepository
+	tokenRepo    storage.RefreshTokenRepository
+	revocations  storage.RevocationStore
 	tokenManager *TokenManager
+	refreshTTL   time.Duration
+	metrics      Metrics
 }
 
-func New(userRepo storage.UserRepository, tm *TokenManager) AuthService {
+func New(
+	uow storage.UnitOfWork,
+	userRepo storage.UserRepository,
+	tokenRepo storage.RefreshTokenRepository,
+	revocations storage.RevocationStore,
+	tm *TokenManager,
+	refreshTTL time.Duration,
+	metrics Metrics,
+) AuthService {
 	return &service{
+		uow:          uow,
 		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		revocations:  revocations,
 		tokenManager: tm,
+		refreshTTL:   refreshTTL,
+		metrics:      metrics,
 	}
 }
 
-func (s *service) Register(ctx context.Context, username, password string) (*models.User, string, error) {
+func (s *service) Register(ctx context.Context, username, password string) (*models.User, *TokenPair, error) {
 	log := logger.
 		FromContext(ctx).
 		With("component", "service", "method", "Register")
 
 	if len(username) < 3 || len(password) < 6 || strings.TrimSpace(username) == "" {
 		log.Warn("invalid registration input", slog.String("username", username))
-		return nil, "", ErrInvalidInput
+		return nil, nil, ErrInvalidInput
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Error("failed to hash password", slog.String("err", err.Error()))
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	user, err := s.userRepo.CreateUser(ctx, username, string(hash))
+	// The user only exists together with its first refresh token.
+	var (
+		user    *models.User
+		refresh string
+	)
+	err = s.uow.WithTx(ctx, func(tx storage.Repos) error {
+		var err error
+		user, err = tx.Users.CreateUser(ctx, username, string(hash))
+		if errors.Is(err, storage.ErrConflict) {
+			return ErrUsernameTaken
+		}
+		if err != nil {
+			return err
+		}
+
+		refresh, err = newTokenFamily(ctx, tx.RefreshTokens, user.ID, s.refreshTTL)
+		return err
+	})
+	if errors.Is(err, ErrUsernameTaken) {
+		log.Warn("username already taken", slog.String("username", username))
+		return nil, nil, err
+	}
 	if err != nil {
 		log.Error("failed to create user", slog.String("err", err.Error()))
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	token, err := s.tokenManager.GenerateToken(user.ID)
+	access, err := s.accessToken(ctx, user)
 	if err != nil {
-		log.Error("failed to generate token", slog.String("err", err.Error()))
-		return nil, "", err
+		log.Error("failed to issue tokens", slog.String("err", err.Error()))
+		return nil, nil, err
 	}
 
+	s.metrics.UserRegistered()
 	log.Info("user registered successfully", slog.Int64("user_id", user.ID))
-	return user, token, nil
+	return user, &TokenPair{AccessToken: access, RefreshToken: refresh}, nil
 }
 
-func (s *service) Login(ctx context.Context, username, password string) (string, error) {
+func (s *service) Login(ctx context.Context, username, password string) (*TokenPair, error) {
 	log := logger.
 		FromContext(ctx).
 		With("component", "service", "method", "Login")
 
 	user, err := s.userRepo.GetUserByUsername(ctx, username)
-	if err != nil {
+	if errors.Is(err, storage.ErrNotFound) {
 		log.Warn("user not found", slog.String("username", username))
-		return "", ErrInvalidCredentials
+		s.metrics.LoginFailed(LoginUnknownUser)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		log.Error("failed to look up user", slog.String("err", err.Error()))
+		s.metrics.LoginFailed(LoginError)
+		return nil, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
 		log.Warn("invalid password", slog.Int64("user_id", user.ID))
-		return "", ErrInvalidCredentials
+		s.metrics.LoginFailed(LoginWrongPassword)
+		return nil, ErrInvalidCredentials
 	}
 
-	token, err := s.tokenManager.GenerateToken(user.ID)
+	tokens, err := s.issueTokens(ctx, user)
 	if err != nil {
-		log.Error("failed to generate token", slog.Int64("user_id", user.ID), slog.String("err", err.Error()))
-		return "", err
+		log.Error("failed to issue tokens", slog.Int64("user_id", user.ID), slog.String("err", err.Error()))
+		s.metrics.LoginFailed(LoginError)
+		return nil, err
 	}
 
+	s.metrics.LoginSucceeded()
 	log.Info("login successful", slog.Int64("user_id", user.ID))
-	return token, nil
+	return tokens, nil
+}
+
+func (s *service) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	log := logger.
+		FromContext(ctx).
+		With("component", "service", "method", "Refresh")
+
+	oldHash := hashRefreshToken(refreshToken)
+
+	raw, hash, err := newRefreshToken()
+	if err != nil {
+		log.Error("failed to generate refresh token", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	next, err := s.tokenRepo.RotateRefreshToken(ctx, oldHash, &models.RefreshToken{
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, s.rejectRefresh(ctx, oldHash)
+	}
+	if err != nil {
+		log.Error("failed to rotate refresh token", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	// Re-read the user so that role changes take effect on the next refresh.
+	user, err := s.userRepo.GetUserByID(ctx, next.UserID)
+	if err != nil {
+		log.Error("failed to load token owner", slog.Int64("user_id", next.UserID), slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	access, err := s.accessToken(ctx, user)
+	if err != nil {
+		log.Error("failed to generate token", slog.Int64("user_id", next.UserID), slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	log.Info("refresh token rotated", slog.Int64("user_id", next.UserID))
+	return &TokenPair{AccessToken: access, RefreshToken: raw}, nil
+}
+
+// rejectRefresh explains why a refresh token could not be rotated. Presenting
+// a token that was already rotated means it leaked, so the whole family is
+// revoked and the legitimate holder has to log in again.
+func (s *service) rejectRefresh(ctx context.Context, tokenHash string) error {
+	log := logger.
+		FromContext(ctx).
+		With("component", "service", "method", "Refresh")
+
+	stored, err := s.tokenRepo.GetRefreshToken(ctx, tokenHash)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Warn("unknown refresh token")
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		log.Error("failed to look up refresh token", slog.String("err", err.Error()))
+		return err
+	}
+
+	if stored.RevokedAt == nil {
+		log.Warn("expired refresh token", slog.Int64("user_id", stored.UserID))
+		return ErrInvalidRefreshToken
+	}
+
+	log.Warn("refresh token reuse detected, revoking family",
+		slog.Int64("user_id", stored.UserID),
+		slog.String("family_id", stored.FamilyID),
+	)
+	if err := s.tokenRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+		log.Error("failed to revoke token family", slog.String("err", err.Error()))
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// issueTokens starts a new refresh token family for the user.
+func (s *service) issueTokens(ctx context.Context, user *models.User) (*TokenPair, error) {
+	access, err := s.accessToken(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := newTokenFamily(ctx, s.tokenRepo, user.ID, s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: access, RefreshToken: raw}, nil
+}
+
+// newTokenFamily stores the first refresh token of a new family and returns
+// it in the raw form handed to the client.
+func newTokenFamily(ctx context.Context, tokens storage.RefreshTokenRepository, userID int64, ttl time.Duration) (string, error) {
+	raw, hash, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tokens.CreateRefreshToken(ctx, &models.RefreshToken{
+		TokenHash: hash,
+		UserID:    userID,
+		FamilyID:  uuid.NewString(),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+func (s *service) accessToken(ctx context.Context, user *models.User) (string, error) {
+	version, err := s.revocations.GetTokenVersion(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	return s.tokenManager.GenerateToken(user.ID, user.Role, version)
+}
+
+func (s *service) Logout(ctx context.Context, claims *Claims, refreshToken string) error {
+	log := logger.
+		FromContext(ctx).
+		With("component", "service", "method", "Logout", "user_id", claims.UserID)
+
+	if err := s.revocations.RevokeToken(ctx, claims.TokenID, claims.ExpiresAt); err != nil {
+		log.Error("failed to revoke access token", slog.String("err", err.Error()))
+		return err
+	}
+
+	if refreshToken != "" {
+		stored, err := s.tokenRepo.GetRefreshToken(ctx, hashRefreshToken(refreshToken))
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			log.Warn("unknown refresh token on logout")
+		case err != nil:
+			log.Error("failed to look up refresh token", slog.String("err", err.Error()))
+			return err
+		case stored.UserID != claims.UserID:
+			log.Warn("refresh token belongs to another user", slog.Int64("owner_id", stored.UserID))
+		default:
+			if err := s.tokenRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+				log.Error("failed to revoke token family", slog.String("err", err.Error()))
+				return err
+			}
+		}
+	}
+
+	log.Info("user logged out")
+	return nil
+}
+
+func (s *service) LogoutAll(ctx context.Context, userID int64) error {
+	log := logger.
+		FromContext(ctx).
+		With("component", "service", "method", "LogoutAll", "user_id", userID)
+
+	version, err := s.revocations.IncrementTokenVersion(ctx, userID)
+	if err != nil {
+		log.Error("failed to bump token version", slog.String("err", err.Error()))
+		return err
+	}
+
+	if err := s.tokenRepo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		log.Error("failed to revoke refresh tokens", slog.String("err", err.Error()))
+		return err
+	}
+
+	log.Info("user logged out everywhere", slog.Int("token_version", version))
+	return nil
 }
 
 func (s *service) GetUser(ctx context.Context, id int64) (*models.User, error) {
@@ -101,15 +343,73 @@ func (s *service) GetUser(ctx context.Context, id int64) (*models.User, error) {
 		With("component", "service", "method", "GetUser", "user_id", id)
 
 	user, err := s.userRepo.GetUserByID(ctx, id)
-	if err != nil {
+	if errors.Is(err, storage.ErrNotFound) {
 		log.Warn("user not found")
-		return nil, ErrInvalidCredentials
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		log.Error("failed to get user", slog.String("err", err.Error()))
+		return nil, err
 	}
 
 	log.Debug("user found")
 	return user, nil
 }
 
-func (s *service) VerifyToken(token string) (int64, error) {
-	return s.tokenManager.ParseToken(token)
+// SetRole changes the role of a user. Access tokens already issued keep the
+// old role until they expire; the next refresh picks up the new one.
+func (s *service) SetRole(ctx context.Context, id int64, role models.Role) (*models.User, error) {
+	log := logger.
+		FromContext(ctx).
+		With("component", "service", "method", "SetRole", "user_id", id)
+
+	if !role.Valid() {
+		log.Warn("invalid role", slog.String("role", string(role)))
+		return nil, ErrInvalidRole
+	}
+
+	user, err := s.userRepo.UpdateUserRole(ctx, id, role)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Warn("user not found")
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		log.Error("failed to update role", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	log.Info("role updated", slog.String("role", string(role)))
+	return user, nil
+}
+
+func (s *service) VerifyToken(ctx context.Context, token string) (*Claims, error) {
+	claims, err := s.tokenManager.ParseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.revocations.IsTokenRevoked(ctx, claims.TokenID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	version, err := s.revocations.GetTokenVersion(ctx, claims.UserID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if claims.Version < version {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+func (s *service) JWKS() JWKS {
+	return s.tokenManager.JWKS()
 }
@@ -3,6 +3,7 @@ package auth_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -12,6 +13,8 @@ import (
 
 	"github.com/justcgh9/vk-internship-application/internal/models"
 	"github.com/justcgh9/vk-internship-application/internal/service/auth"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+	"github.com/justcgh9/vk-internship-application/internal/storage/memory"
 )
 
 // --- Mocks ---
@@ -44,18 +47,108 @@ func (m *mockUserRepo) GetUserByID(ctx context.Context, id int64) (*models.User,
 	return nil, args.Error(1)
 }
 
+func (m *mockUserRepo) UpdateUserRole(ctx context.Context, id int64, role models.Role) (*models.User, error) {
+	args := m.Called(ctx, id, role)
+	if usr := args.Get(0); usr != nil {
+		return usr.(*models.User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type mockTokenRepo struct {
+	mock.Mock
+}
+
+func (m *mockTokenRepo) CreateRefreshToken(ctx context.Context, t *models.RefreshToken) (*models.RefreshToken, error) {
+	args := m.Called(ctx, t)
+	if tok := args.Get(0); tok != nil {
+		return tok.(*models.RefreshToken), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockTokenRepo) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	args := m.Called(ctx, tokenHash)
+	if tok := args.Get(0); tok != nil {
+		return tok.(*models.RefreshToken), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockTokenRepo) RotateRefreshToken(ctx context.Context, oldHash string, next *models.RefreshToken) (*models.RefreshToken, error) {
+	args := m.Called(ctx, oldHash, next)
+	if tok := args.Get(0); tok != nil {
+		return tok.(*models.RefreshToken), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockTokenRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	args := m.Called(ctx, familyID)
+	return args.Error(0)
+}
+
+func (m *mockTokenRepo) RevokeUserRefreshTokens(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
 // --- Helpers ---
 
 func newTokenManager() *auth.TokenManager {
 	return auth.NewTokenManager("secret", time.Hour)
 }
 
+// inlineTx runs units of work straight against the mocked repositories.
+type inlineTx struct {
+	users  storage.UserRepository
+	tokens storage.RefreshTokenRepository
+}
+
+func (u inlineTx) WithTx(ctx context.Context, fn func(tx storage.Repos) error) error {
+	return u.WithTxOptions(ctx, storage.DefaultTxOptions, fn)
+}
+
+func (u inlineTx) WithTxOptions(_ context.Context, _ storage.TxOptions, fn func(tx storage.Repos) error) error {
+	return fn(storage.Repos{Users: u.users, RefreshTokens: u.tokens})
+}
+
+func newService(
+	users storage.UserRepository,
+	tokens storage.RefreshTokenRepository,
+	revocations storage.RevocationStore,
+	tm *auth.TokenManager,
+) auth.AuthService {
+	return auth.New(inlineTx{users, tokens}, users, tokens, revocations, tm, time.Hour, auth.NopMetrics{})
+}
+
+// everyUser knows every user ID, so that token versions can be read without
+// setting up the users.
+type everyUser struct{ storage.UserRepository }
+
+func (everyUser) GetUserByID(_ context.Context, id int64) (*models.User, error) {
+	return &models.User{ID: id}, nil
+}
+
+func newRevocationStore() *memory.RevocationStore {
+	return memory.NewRevocationStore(everyUser{})
+}
+
+func newTokenRepo() *mockTokenRepo {
+	tokenRepo := new(mockTokenRepo)
+	tokenRepo.
+		On("CreateRefreshToken", mock.Anything, mock.Anything).
+		Return(&models.RefreshToken{ID: 1}, nil).
+		Maybe()
+	return tokenRepo
+}
+
 // --- Tests: Register ---
 
 func TestRegister_Success(t *testing.T) {
 	repo := new(mockUserRepo)
 	tm := newTokenManager()
-	svc := auth.New(repo, tm)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), tm)
 
 	username := "alice"
 	password := "securepass"
@@ -66,18 +159,19 @@ func TestRegister_Success(t *testing.T) {
 	}, nil)
 
 	ctx := context.Background()
-	usr, token, err := svc.Register(ctx, username, password)
+	usr, tokens, err := svc.Register(ctx, username, password)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(1), usr.ID)
-	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
 	repo.AssertExpectations(t)
 }
 
 func TestRegister_InvalidInput(t *testing.T) {
 	repo := new(mockUserRepo)
 	tm := newTokenManager()
-	svc := auth.New(repo, tm)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), tm)
 
 	tests := []struct {
 		name     string
@@ -100,7 +194,7 @@ func TestRegister_InvalidInput(t *testing.T) {
 func TestRegister_RepoError(t *testing.T) {
 	repo := new(mockUserRepo)
 	tm := newTokenManager()
-	svc := auth.New(repo, tm)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), tm)
 
 	repo.On("CreateUser", mock.Anything, "bob", mock.Anything).Return(nil, errors.New("db error"))
 
@@ -109,12 +203,52 @@ func TestRegister_RepoError(t *testing.T) {
 	assert.Contains(t, err.Error(), "db error")
 }
 
+func TestRegister_UsernameTaken(t *testing.T) {
+	repo := new(mockUserRepo)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), newTokenManager())
+
+	repo.On("CreateUser", mock.Anything, "bob", mock.Anything).
+		Return(nil, fmt.Errorf("%w: users_username_key", storage.ErrConflict))
+
+	_, _, err := svc.Register(context.Background(), "bob", "password123")
+	assert.ErrorIs(t, err, auth.ErrUsernameTaken)
+}
+
+func TestRegister_RefreshTokenFailure(t *testing.T) {
+	repo := new(mockUserRepo)
+	tokenRepo := new(mockTokenRepo)
+	svc := newService(repo, tokenRepo, newRevocationStore(), newTokenManager())
+
+	repo.On("CreateUser", mock.Anything, "bob", mock.Anything).Return(&models.User{ID: 1, Username: "bob"}, nil)
+	tokenRepo.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(nil, errors.New("db error"))
+
+	_, _, err := svc.Register(context.Background(), "bob", "password123")
+	assert.Error(t, err)
+}
+
+func TestRegister_CommitsUserWithRefreshToken(t *testing.T) {
+	store := memory.NewStorage()
+	svc := auth.New(store, store, store, memory.NewRevocationStore(store), newTokenManager(), time.Hour, auth.NopMetrics{})
+	ctx := context.Background()
+
+	user, tokens, err := svc.Register(ctx, "bob", "password123")
+	assert.NoError(t, err)
+
+	_, err = store.GetUserByID(ctx, user.ID)
+	assert.NoError(t, err)
+	_, err = svc.Refresh(ctx, tokens.RefreshToken)
+	assert.NoError(t, err)
+
+	_, _, err = svc.Register(ctx, "bob", "password456")
+	assert.ErrorIs(t, err, auth.ErrUsernameTaken)
+}
+
 // --- Tests: Login ---
 
 func TestLogin_Success(t *testing.T) {
 	repo := new(mockUserRepo)
 	tm := newTokenManager()
-	svc := auth.New(repo, tm)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), tm)
 
 	hash, _ := bcrypt.GenerateFromPassword([]byte("mypassword"), bcrypt.DefaultCost)
 
@@ -124,16 +258,17 @@ func TestLogin_Success(t *testing.T) {
 		PasswordHash: string(hash),
 	}, nil)
 
-	token, err := svc.Login(context.Background(), "john", "mypassword")
+	tokens, err := svc.Login(context.Background(), "john", "mypassword")
 	assert.NoError(t, err)
-	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
 	repo.AssertExpectations(t)
 }
 
 func TestLogin_InvalidPassword(t *testing.T) {
 	repo := new(mockUserRepo)
 	tm := newTokenManager()
-	svc := auth.New(repo, tm)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), tm)
 
 	hash, _ := bcrypt.GenerateFromPassword([]byte("rightpass"), bcrypt.DefaultCost)
 
@@ -150,20 +285,109 @@ func TestLogin_InvalidPassword(t *testing.T) {
 func TestLogin_UserNotFound(t *testing.T) {
 	repo := new(mockUserRepo)
 	tm := newTokenManager()
-	svc := auth.New(repo, tm)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), tm)
 
-	repo.On("GetUserByUsername", mock.Anything, "ghost").Return(nil, errors.New("not found"))
+	repo.On("GetUserByUsername", mock.Anything, "ghost").Return(nil, storage.ErrNotFound)
 
 	_, err := svc.Login(context.Background(), "ghost", "irrelevant")
 	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
 }
 
+func TestLogin_RepoError(t *testing.T) {
+	repo := new(mockUserRepo)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), newTokenManager())
+
+	repo.On("GetUserByUsername", mock.Anything, "john").Return(nil, errors.New("db error"))
+
+	_, err := svc.Login(context.Background(), "john", "irrelevant")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, auth.ErrInvalidCredentials)
+}
+
+// --- Tests: Refresh ---
+
+func TestRefresh_Success(t *testing.T) {
+	repo := new(mockUserRepo)
+	tokenRepo := new(mockTokenRepo)
+	svc := newService(repo, tokenRepo, newRevocationStore(), newTokenManager())
+
+	tokenRepo.
+		On("RotateRefreshToken", mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.RefreshToken{ID: 2, UserID: 7, FamilyID: "family"}, nil)
+	repo.
+		On("GetUserByID", mock.Anything, int64(7)).
+		Return(&models.User{ID: 7, Username: "promoted", Role: models.RoleModerator}, nil)
+
+	tokens, err := svc.Refresh(context.Background(), "old-refresh")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.NotEqual(t, "old-refresh", tokens.RefreshToken)
+
+	claims, err := newTokenManager().ParseToken(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), claims.UserID)
+	assert.Equal(t, models.RoleModerator, claims.Role)
+}
+
+func TestRefresh_UnknownToken(t *testing.T) {
+	tokenRepo := new(mockTokenRepo)
+	svc := newService(new(mockUserRepo), tokenRepo, newRevocationStore(), newTokenManager())
+
+	tokenRepo.
+		On("RotateRefreshToken", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, storage.ErrNotFound)
+	tokenRepo.
+		On("GetRefreshToken", mock.Anything, mock.Anything).
+		Return(nil, storage.ErrNotFound)
+
+	_, err := svc.Refresh(context.Background(), "garbage")
+	assert.ErrorIs(t, err, auth.ErrInvalidRefreshToken)
+	tokenRepo.AssertNotCalled(t, "RevokeRefreshTokenFamily", mock.Anything, mock.Anything)
+}
+
+func TestRefresh_Expired(t *testing.T) {
+	tokenRepo := new(mockTokenRepo)
+	svc := newService(new(mockUserRepo), tokenRepo, newRevocationStore(), newTokenManager())
+
+	tokenRepo.
+		On("RotateRefreshToken", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, storage.ErrNotFound)
+	tokenRepo.
+		On("GetRefreshToken", mock.Anything, mock.Anything).
+		Return(&models.RefreshToken{UserID: 7, FamilyID: "family", ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+
+	_, err := svc.Refresh(context.Background(), "expired")
+	assert.ErrorIs(t, err, auth.ErrInvalidRefreshToken)
+	tokenRepo.AssertNotCalled(t, "RevokeRefreshTokenFamily", mock.Anything, mock.Anything)
+}
+
+func TestRefresh_ReuseRevokesFamily(t *testing.T) {
+	tokenRepo := new(mockTokenRepo)
+	svc := newService(new(mockUserRepo), tokenRepo, newRevocationStore(), newTokenManager())
+
+	revokedAt := time.Now().Add(-time.Minute)
+	tokenRepo.
+		On("RotateRefreshToken", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, storage.ErrNotFound)
+	tokenRepo.
+		On("GetRefreshToken", mock.Anything, mock.Anything).
+		Return(&models.RefreshToken{UserID: 7, FamilyID: "family", RevokedAt: &revokedAt}, nil)
+	tokenRepo.
+		On("RevokeRefreshTokenFamily", mock.Anything, "family").
+		Return(nil)
+
+	_, err := svc.Refresh(context.Background(), "replayed")
+	assert.ErrorIs(t, err, auth.ErrRefreshTokenReused)
+	tokenRepo.AssertExpectations(t)
+}
+
 // --- Tests: GetUser ---
 
 func TestGetUser_Success(t *testing.T) {
 	repo := new(mockUserRepo)
 	tm := newTokenManager()
-	svc := auth.New(repo, tm)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), tm)
 
 	repo.On("GetUserByID", mock.Anything, int64(42)).Return(&models.User{
 		ID:       42,
@@ -178,29 +402,194 @@ func TestGetUser_Success(t *testing.T) {
 func TestGetUser_Error(t *testing.T) {
 	repo := new(mockUserRepo)
 	tm := newTokenManager()
-	svc := auth.New(repo, tm)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), tm)
 
 	repo.On("GetUserByID", mock.Anything, int64(99)).Return(nil, errors.New("db error"))
 
 	_, err := svc.GetUser(context.Background(), 99)
-	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "db error")
+}
+
+func TestGetUser_NotFound(t *testing.T) {
+	repo := new(mockUserRepo)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), newTokenManager())
+
+	repo.On("GetUserByID", mock.Anything, int64(99)).Return(nil, storage.ErrNotFound)
+
+	_, err := svc.GetUser(context.Background(), 99)
+	assert.ErrorIs(t, err, auth.ErrUserNotFound)
+}
+
+// --- Tests: SetRole ---
+
+func TestSetRole_Success(t *testing.T) {
+	repo := new(mockUserRepo)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), newTokenManager())
+
+	repo.On("UpdateUserRole", mock.Anything, int64(4), models.RoleModerator).
+		Return(&models.User{ID: 4, Role: models.RoleModerator}, nil)
+
+	usr, err := svc.SetRole(context.Background(), 4, models.RoleModerator)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleModerator, usr.Role)
+}
+
+func TestSetRole_InvalidRole(t *testing.T) {
+	repo := new(mockUserRepo)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), newTokenManager())
+
+	_, err := svc.SetRole(context.Background(), 4, models.Role("owner"))
+	assert.ErrorIs(t, err, auth.ErrInvalidRole)
+	repo.AssertNotCalled(t, "UpdateUserRole", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSetRole_UserNotFound(t *testing.T) {
+	repo := new(mockUserRepo)
+	svc := newService(repo, newTokenRepo(), newRevocationStore(), newTokenManager())
+
+	repo.On("UpdateUserRole", mock.Anything, int64(4), models.RoleAdmin).Return(nil, storage.ErrNotFound)
+
+	_, err := svc.SetRole(context.Background(), 4, models.RoleAdmin)
+	assert.ErrorIs(t, err, auth.ErrUserNotFound)
 }
 
 // --- Tests: VerifyToken ---
 
 func TestVerifyToken_Success(t *testing.T) {
 	tm := newTokenManager()
-	token, err := tm.GenerateToken(123)
+	svc := newService(new(mockUserRepo), newTokenRepo(), newRevocationStore(), tm)
+
+	token, err := tm.GenerateToken(123, models.RoleUser, 0)
 	assert.NoError(t, err)
 
-	userID, err := tm.ParseToken(token)
+	claims, err := svc.VerifyToken(context.Background(), token)
 	assert.NoError(t, err)
-	assert.Equal(t, int64(123), userID)
+	assert.Equal(t, int64(123), claims.UserID)
 }
 
 func TestVerifyToken_Invalid(t *testing.T) {
+	svc := newService(new(mockUserRepo), newTokenRepo(), newRevocationStore(), newTokenManager())
+
+	_, err := svc.VerifyToken(context.Background(), "invalid.jwt.token")
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestVerifyToken_DeletedUser(t *testing.T) {
+	repo := new(mockUserRepo)
 	tm := newTokenManager()
+	svc := newService(repo, newTokenRepo(), memory.NewRevocationStore(repo), tm)
+
+	repo.On("GetUserByID", mock.Anything, int64(123)).Return(nil, storage.ErrNotFound)
+
+	token, err := tm.GenerateToken(123, models.RoleUser, 0)
+	assert.NoError(t, err)
 
-	_, err := tm.ParseToken("invalid.jwt.token")
+	_, err = svc.VerifyToken(context.Background(), token)
 	assert.ErrorIs(t, err, auth.ErrInvalidToken)
 }
+
+// --- Tests: Logout ---
+
+func TestLogout_RevokesAccessToken(t *testing.T) {
+	tm := newTokenManager()
+	svc := newService(new(mockUserRepo), newTokenRepo(), newRevocationStore(), tm)
+	ctx := context.Background()
+
+	token, err := tm.GenerateToken(5, models.RoleUser, 0)
+	assert.NoError(t, err)
+
+	claims, err := svc.VerifyToken(ctx, token)
+	assert.NoError(t, err)
+
+	assert.NoError(t, svc.Logout(ctx, claims, ""))
+
+	_, err = svc.VerifyToken(ctx, token)
+	assert.ErrorIs(t, err, auth.ErrTokenRevoked)
+}
+
+func TestLogout_RevokesRefreshFamily(t *testing.T) {
+	tokenRepo := new(mockTokenRepo)
+	svc := newService(new(mockUserRepo), tokenRepo, newRevocationStore(), newTokenManager())
+
+	tokenRepo.
+		On("GetRefreshToken", mock.Anything, mock.Anything).
+		Return(&models.RefreshToken{UserID: 5, FamilyID: "family"}, nil)
+	tokenRepo.
+		On("RevokeRefreshTokenFamily", mock.Anything, "family").
+		Return(nil)
+
+	claims := &auth.Claims{UserID: 5, TokenID: "jti", ExpiresAt: time.Now().Add(time.Minute)}
+	assert.NoError(t, svc.Logout(context.Background(), claims, "refresh"))
+	tokenRepo.AssertExpectations(t)
+}
+
+func TestLogout_IgnoresForeignRefreshToken(t *testing.T) {
+	tokenRepo := new(mockTokenRepo)
+	svc := newService(new(mockUserRepo), tokenRepo, newRevocationStore(), newTokenManager())
+
+	tokenRepo.
+		On("GetRefreshToken", mock.Anything, mock.Anything).
+		Return(&models.RefreshToken{UserID: 6, FamilyID: "family"}, nil)
+
+	claims := &auth.Claims{UserID: 5, TokenID: "jti", ExpiresAt: time.Now().Add(time.Minute)}
+	assert.NoError(t, svc.Logout(context.Background(), claims, "refresh"))
+	tokenRepo.AssertNotCalled(t, "RevokeRefreshTokenFamily", mock.Anything, mock.Anything)
+}
+
+func TestLogoutAll_InvalidatesIssuedTokens(t *testing.T) {
+	tm := newTokenManager()
+	tokenRepo := new(mockTokenRepo)
+	svc := newService(new(mockUserRepo), tokenRepo, newRevocationStore(), tm)
+	ctx := context.Background()
+
+	tokenRepo.On("RevokeUserRefreshTokens", mock.Anything, int64(5)).Return(nil)
+
+	token, err := tm.GenerateToken(5, models.RoleUser, 0)
+	assert.NoError(t, err)
+
+	assert.NoError(t, svc.LogoutAll(ctx, 5))
+
+	_, err = svc.VerifyToken(ctx, token)
+	assert.ErrorIs(t, err, auth.ErrTokenRevoked)
+
+	fresh, err := tm.GenerateToken(5, models.RoleUser, 1)
+	assert.NoError(t, err)
+
+	_, err = svc.VerifyToken(ctx, fresh)
+	assert.NoError(t, err)
+	tokenRepo.AssertExpectations(t)
+}
+
+type recordedMetrics struct {
+	registered   int
+	logins       int
+	loginFailure []string
+}
+
+func (m *recordedMetrics) UserRegistered()           { m.registered++ }
+func (m *recordedMetrics) LoginSucceeded()           { m.logins++ }
+func (m *recordedMetrics) LoginFailed(reason string) { m.loginFailure = append(m.loginFailure, reason) }
+
+func TestMetrics(t *testing.T) {
+	store := memory.NewStorage()
+	metrics := new(recordedMetrics)
+	svc := auth.New(store, store, store, memory.NewRevocationStore(store), newTokenManager(), time.Hour, metrics)
+	ctx := context.Background()
+
+	_, _, err := svc.Register(ctx, "alice", "password")
+	assert.NoError(t, err)
+	_, _, err = svc.Register(ctx, "alice", "password")
+	assert.ErrorIs(t, err, auth.ErrUsernameTaken)
+
+	_, err = svc.Login(ctx, "alice", "password")
+	assert.NoError(t, err)
+	_, err = svc.Login(ctx, "alice", "wrong")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	_, err = svc.Login(ctx, "bob", "password")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+
+	assert.Equal(t, 1, metrics.registered)
+	assert.Equal(t, 1, metrics.logins)
+	assert.Equal(t, []string{auth.LoginWrongPassword, auth.LoginUnknownUser}, metrics.loginFailure)
+}
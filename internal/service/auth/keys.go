@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrUnknownSigningKey    = errors.New("signing key not found")
+	ErrKeyMismatch          = errors.New("public key does not match the private key")
+)
+
+// Key is a JWT key identified by its kid. Verification-only keys (previous
+// rotations kept around until their tokens expire) have no private part.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	private any
+	public  any
+}
+
+// NewHMACKey wraps a shared secret. It is never published in the JWKS.
+func NewHMACKey(id, secret string) *Key {
+	return &Key{
+		ID:      id,
+		Method:  jwt.SigningMethodHS256,
+		private: []byte(secret),
+		public:  []byte(secret),
+	}
+}
+
+// LoadKey reads an RS256 or EdDSA key from PEM files. Either path may be
+// empty: the public key is derived from the private one when possible, and a
+// key without a private part can only verify tokens. When both are given,
+// they must be halves of the same key pair.
+func LoadKey(id, algorithm, privateKeyPath, publicKeyPath string) (*Key, error) {
+	key := &Key{ID: id}
+
+	switch algorithm {
+	case AlgorithmRS256:
+		key.Method = jwt.SigningMethodRS256
+	case AlgorithmEdDSA:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	if privateKeyPath != "" {
+		data, err := os.ReadFile(privateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read private key %q: %w", id, err)
+		}
+
+		var signer crypto.Signer
+		if algorithm == AlgorithmRS256 {
+			signer, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+		} else {
+			var pk crypto.PrivateKey
+			pk, err = jwt.ParseEdPrivateKeyFromPEM(data)
+			signer, _ = pk.(crypto.Signer)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse private key %q: %w", id, err)
+		}
+
+		key.private = signer
+		key.public = signer.Public()
+	}
+
+	if publicKeyPath != "" {
+		data, err := os.ReadFile(publicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read public key %q: %w", id, err)
+		}
+
+		var public crypto.PublicKey
+		if algorithm == AlgorithmRS256 {
+			public, err = jwt.ParseRSAPublicKeyFromPEM(data)
+		} else {
+			public, err = jwt.ParseEdPublicKeyFromPEM(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse public key %q: %w", id, err)
+		}
+
+		// Otherwise tokens signed with the private key would fail to verify
+		// against the published one.
+		if derived, ok := key.public.(interface{ Equal(crypto.PublicKey) bool }); ok && !derived.Equal(public) {
+			return nil, fmt.Errorf("key %q: %w", id, ErrKeyMismatch)
+		}
+		key.public = public
+	}
+
+	if key.public == nil {
+		return nil, fmt.Errorf("key %q: no private or public key file given", id)
+	}
+
+	return key, nil
+}
+
+func (k *Key) canSign() bool {
+	return k.private != nil
+}
+
+func (k *Key) isSymmetric() bool {
+	_, ok := k.Method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+// JWK is the public part of a key in RFC 7517 format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// OKP (Ed25519)
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k *Key) jwk() (JWK, bool) {
+	jwk := JWK{
+		KeyID:     k.ID,
+		Use:       "sig",
+		Algorithm: k.Method.Alg(),
+	}
+
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
@@ -0,0 +1,202 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/service/auth"
+)
+
+// writeKeyPair stores the key as PKCS#8 and its public part as PKIX PEM files.
+func writeKeyPair(t *testing.T, name string, priv crypto.Signer) (privatePath, publicPath string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(priv.Public())
+	require.NoError(t, err)
+
+	privatePath = filepath.Join(dir, name+".pem")
+	publicPath = filepath.Join(dir, name+".pub.pem")
+	require.NoError(t, os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600))
+	require.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o600))
+	return privatePath, publicPath
+}
+
+func newEdKeyFiles(t *testing.T, name string) (string, string) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return writeKeyPair(t, name, priv)
+}
+
+func newRSAKeyFiles(t *testing.T, name string) (string, string) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return writeKeyPair(t, name, priv)
+}
+
+func TestKeyedTokenManager_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		files     func(*testing.T, string) (string, string)
+	}{
+		{"EdDSA", auth.AlgorithmEdDSA, newEdKeyFiles},
+		{"RS256", auth.AlgorithmRS256, newRSAKeyFiles},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privatePath, _ := tt.files(t, "k1")
+
+			key, err := auth.LoadKey("k1", tt.algorithm, privatePath, "")
+			require.NoError(t, err)
+
+			tm, err := auth.NewKeyedTokenManager("k1", []*auth.Key{key}, time.Minute)
+			require.NoError(t, err)
+
+			tokenStr, err := tm.GenerateToken(42, models.RoleUser, 0)
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(tokenStr, jwt.MapClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, "k1", parsed.Header["kid"])
+			assert.Equal(t, tt.algorithm, parsed.Header["alg"])
+
+			claims, err := tm.ParseToken(tokenStr)
+			require.NoError(t, err)
+			assert.Equal(t, int64(42), claims.UserID)
+		})
+	}
+}
+
+func TestKeyedTokenManager_Rotation(t *testing.T) {
+	oldPrivate, oldPublic := newEdKeyFiles(t, "old")
+	newPrivate, _ := newEdKeyFiles(t, "new")
+
+	oldKey, err := auth.LoadKey("old", auth.AlgorithmEdDSA, oldPrivate, "")
+	require.NoError(t, err)
+	before, err := auth.NewKeyedTokenManager("old", []*auth.Key{oldKey}, time.Minute)
+	require.NoError(t, err)
+
+	issued, err := before.GenerateToken(7, models.RoleUser, 0)
+	require.NoError(t, err)
+
+	// After rotation the old key is kept for verification only.
+	retired, err := auth.LoadKey("old", auth.AlgorithmEdDSA, "", oldPublic)
+	require.NoError(t, err)
+	current, err := auth.LoadKey("new", auth.AlgorithmEdDSA, newPrivate, "")
+	require.NoError(t, err)
+	after, err := auth.NewKeyedTokenManager("new", []*auth.Key{current, retired}, time.Minute)
+	require.NoError(t, err)
+
+	claims, err := after.ParseToken(issued)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), claims.UserID)
+
+	jwks := after.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "new", jwks.Keys[0].KeyID)
+	assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+	assert.Equal(t, "Ed25519", jwks.Keys[0].Curve)
+	assert.Equal(t, "old", jwks.Keys[1].KeyID)
+}
+
+func TestKeyedTokenManager_UnknownKid(t *testing.T) {
+	privateA, _ := newEdKeyFiles(t, "a")
+	privateB, _ := newEdKeyFiles(t, "b")
+
+	keyA, err := auth.LoadKey("a", auth.AlgorithmEdDSA, privateA, "")
+	require.NoError(t, err)
+	keyB, err := auth.LoadKey("b", auth.AlgorithmEdDSA, privateB, "")
+	require.NoError(t, err)
+
+	tmA, err := auth.NewKeyedTokenManager("a", []*auth.Key{keyA}, time.Minute)
+	require.NoError(t, err)
+	tmB, err := auth.NewKeyedTokenManager("b", []*auth.Key{keyB}, time.Minute)
+	require.NoError(t, err)
+
+	tokenStr, err := tmA.GenerateToken(1, models.RoleUser, 0)
+	require.NoError(t, err)
+
+	_, err = tmB.ParseToken(tokenStr)
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestKeyedTokenManager_RejectsAlgorithmConfusion(t *testing.T) {
+	_, publicPath := newRSAKeyFiles(t, "rsa")
+	pemBytes, err := os.ReadFile(publicPath)
+	require.NoError(t, err)
+
+	key, err := auth.LoadKey("rsa", auth.AlgorithmRS256, "", publicPath)
+	require.NoError(t, err)
+	tm, err := auth.NewKeyedTokenManager("rsa", []*auth.Key{key}, time.Minute)
+	assert.Error(t, err, "verify-only key must not be used for signing")
+	assert.Nil(t, tm)
+
+	// An HS256 token keyed with the public PEM must not pass as RS256.
+	privatePath, _ := newRSAKeyFiles(t, "signer")
+	signer, err := auth.LoadKey("rsa", auth.AlgorithmRS256, privatePath, "")
+	require.NoError(t, err)
+	tm, err = auth.NewKeyedTokenManager("rsa", []*auth.Key{signer}, time.Minute)
+	require.NoError(t, err)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1,
+		"jti":     "forged",
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	})
+	forged.Header["kid"] = "rsa"
+	forgedStr, err := forged.SignedString(pemBytes)
+	require.NoError(t, err)
+
+	_, err = tm.ParseToken(forgedStr)
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestLoadKey_UnsupportedAlgorithm(t *testing.T) {
+	_, err := auth.LoadKey("k", "ES256", "", "")
+	assert.ErrorIs(t, err, auth.ErrUnsupportedAlgorithm)
+}
+
+func TestLoadKey_PublicKeyMismatch(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		newFiles  func(t *testing.T, name string) (string, string)
+	}{
+		{auth.AlgorithmEdDSA, newEdKeyFiles},
+		{auth.AlgorithmRS256, newRSAKeyFiles},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			privatePath, publicPath := tt.newFiles(t, "a")
+			_, otherPublic := tt.newFiles(t, "b")
+
+			_, err := auth.LoadKey("k", tt.algorithm, privatePath, otherPublic)
+			assert.ErrorIs(t, err, auth.ErrKeyMismatch)
+
+			_, err = auth.LoadKey("k", tt.algorithm, privatePath, publicPath)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTokenManager_HMACHasEmptyJWKS(t *testing.T) {
+	tm := auth.NewTokenManager("secret", time.Minute)
+	assert.Empty(t, tm.JWKS().Keys)
+}
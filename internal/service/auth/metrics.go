@@ -0,0 +1,22 @@
+package auth
+
+// Reasons a login fails, as reported to Metrics.
+const (
+	LoginUnknownUser   = "unknown_user"
+	LoginWrongPassword = "wrong_password"
+	LoginError         = "error"
+)
+
+// Metrics counts account events for dashboards and alerts.
+type Metrics interface {
+	UserRegistered()
+	LoginSucceeded()
+	LoginFailed(reason string)
+}
+
+// NopMetrics discards everything.
+type NopMetrics struct{}
+
+func (NopMetrics) UserRegistered()    {}
+func (NopMetrics) LoginSucceeded()    {}
+func (NopMetrics) LoginFailed(string) {}
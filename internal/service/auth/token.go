@@ -2,59 +2,166 @@ package auth
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/justcgh9/vk-internship-application/internal/models"
 )
 
 var (
 	ErrInvalidToken = errors.New("invalid token")
 )
 
+// defaultKeyID identifies the shared secret when no key set is configured.
+const defaultKeyID = "default"
+
+// Claims is the verified content of an access token.
+type Claims struct {
+	UserID    int64
+	Role      models.Role
+	TokenID   string
+	Version   int
+	ExpiresAt time.Time
+}
+
 type TokenManager struct {
-	secret     string
+	signingKey *Key
+	keys       map[string]*Key
 	expiration time.Duration
 }
 
+// NewTokenManager signs and verifies tokens with a single HS256 shared secret.
 func NewTokenManager(secret string, expiration time.Duration) *TokenManager {
+	key := NewHMACKey(defaultKeyID, secret)
 	return &TokenManager{
-		secret:     secret,
+		signingKey: key,
+		keys:       map[string]*Key{key.ID: key},
 		expiration: expiration,
 	}
 }
 
-func (tm *TokenManager) GenerateToken(userID int64) (string, error) {
+// NewKeyedTokenManager signs tokens with the key identified by signingKeyID
+// and accepts tokens signed by any of the given keys, which allows rotating
+// keys without invalidating tokens that are still in flight.
+func NewKeyedTokenManager(signingKeyID string, keys []*Key, expiration time.Duration) (*TokenManager, error) {
+	tm := &TokenManager{
+		keys:       make(map[string]*Key, len(keys)),
+		expiration: expiration,
+	}
+
+	for _, key := range keys {
+		if _, ok := tm.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		tm.keys[key.ID] = key
+	}
+
+	signingKey, ok := tm.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSigningKey, signingKeyID)
+	}
+	if !signingKey.canSign() {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKeyID)
+	}
+	tm.signingKey = signingKey
+
+	return tm, nil
+}
+
+// GenerateToken issues an access token for the user. version is the user's
+// current token version: bumping it invalidates every token issued before.
+func (tm *TokenManager) GenerateToken(userID int64, role models.Role, version int) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
+		"role":    string(role),
+		"ver":     version,
+		"jti":     uuid.NewString(),
 		"exp":     time.Now().Add(tm.expiration).Unix(),
 		"iat":     time.Now().Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(tm.secret))
+	token := jwt.NewWithClaims(tm.signingKey.Method, claims)
+	token.Header["kid"] = tm.signingKey.ID
+	return token.SignedString(tm.signingKey.private)
 }
 
-func (tm *TokenManager) ParseToken(tokenStr string) (int64, error) {
+func (tm *TokenManager) ParseToken(tokenStr string) (*Claims, error) {
 	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		// validate alg
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			kid = defaultKeyID
+		}
+
+		key, ok := tm.keys[kid]
+		if !ok {
 			return nil, ErrInvalidToken
 		}
-		return []byte(tm.secret), nil
+
+		// validate alg against the key, never trust the header alone
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return key.public, nil
 	})
 	if err != nil || !token.Valid {
-		return 0, ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return 0, ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
 	userIDFloat, ok := claims["user_id"].(float64)
 	if !ok {
-		return 0, ErrInvalidToken
+		return nil, ErrInvalidToken
+	}
+
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return nil, ErrInvalidToken
+	}
+
+	role := models.RoleUser
+	if r, ok := claims["role"].(string); ok && r != "" {
+		role = models.Role(r)
+	}
+	if !role.Valid() {
+		return nil, ErrInvalidToken
 	}
 
-	return int64(userIDFloat), nil
+	version, _ := claims["ver"].(float64)
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return nil, ErrInvalidToken
+	}
+
+	return &Claims{
+		UserID:    int64(userIDFloat),
+		Role:      role,
+		TokenID:   jti,
+		Version:   int(version),
+		ExpiresAt: exp.Time,
+	}, nil
+}
+
+// JWKS returns the public verification keys. Shared secrets are never
+// published, so an HS256-only manager yields an empty set.
+func (tm *TokenManager) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range tm.keys {
+		if key.isSymmetric() {
+			continue
+		}
+		if jwk, ok := key.jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
 }
@@ -7,24 +7,28 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/justcgh9/vk-internship-application/internal/models"
 	"github.com/justcgh9/vk-internship-application/internal/service/auth"
 )
 
 func TestGenerateAndParseToken_Success(t *testing.T) {
 	tm := auth.NewTokenManager("testsecret", time.Minute)
 
-	tokenStr, err := tm.GenerateToken(42)
+	tokenStr, err := tm.GenerateToken(42, models.RoleAdmin, 3)
 	assert.NoError(t, err)
 
-	userID, err := tm.ParseToken(tokenStr)
+	claims, err := tm.ParseToken(tokenStr)
 	assert.NoError(t, err)
-	assert.Equal(t, int64(42), userID)
+	assert.Equal(t, int64(42), claims.UserID)
+	assert.Equal(t, models.RoleAdmin, claims.Role)
+	assert.Equal(t, 3, claims.Version)
+	assert.NotEmpty(t, claims.TokenID)
 }
 
 func TestParseToken_InvalidSignature(t *testing.T) {
 
 	tm := auth.NewTokenManager("secretA", time.Minute)
-	tokenStr, err := tm.GenerateToken(1)
+	tokenStr, err := tm.GenerateToken(1, models.RoleUser, 0)
 	assert.NoError(t, err)
 
 	tm2 := auth.NewTokenManager("secretB", time.Minute)
@@ -42,13 +46,58 @@ func TestParseToken_InvalidFormat(t *testing.T) {
 func TestParseToken_Expired(t *testing.T) {
 	tm := auth.NewTokenManager("secret", -time.Second)
 
-	tokenStr, err := tm.GenerateToken(123)
+	tokenStr, err := tm.GenerateToken(123, models.RoleUser, 0)
 	assert.NoError(t, err)
 
 	_, err = tm.ParseToken(tokenStr)
 	assert.ErrorIs(t, err, auth.ErrInvalidToken)
 }
 
+func TestGenerateToken_UniqueTokenIDs(t *testing.T) {
+	tm := auth.NewTokenManager("secret", time.Minute)
+
+	first, err := tm.GenerateToken(1, models.RoleUser, 0)
+	assert.NoError(t, err)
+	second, err := tm.GenerateToken(1, models.RoleUser, 0)
+	assert.NoError(t, err)
+
+	firstClaims, err := tm.ParseToken(first)
+	assert.NoError(t, err)
+	secondClaims, err := tm.ParseToken(second)
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, firstClaims.TokenID, secondClaims.TokenID)
+}
+
+func TestParseToken_UnknownRole(t *testing.T) {
+	claims := jwt.MapClaims{
+		"user_id": 1,
+		"role":    "superuser",
+		"jti":     "jti",
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenStr, _ := token.SignedString([]byte("secret"))
+
+	tm := auth.NewTokenManager("secret", time.Minute)
+	_, err := tm.ParseToken(tokenStr)
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestParseToken_MissingTokenID(t *testing.T) {
+	claims := jwt.MapClaims{
+		"user_id": 1,
+		"exp":     time.Now().Add(time.Minute).Unix(),
+		"iat":     time.Now().Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenStr, _ := token.SignedString([]byte("secret"))
+
+	tm := auth.NewTokenManager("secret", time.Minute)
+	_, err := tm.ParseToken(tokenStr)
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
 func TestParseToken_MissingUserIDClaim(t *testing.T) {
 
 	claims := jwt.MapClaims{
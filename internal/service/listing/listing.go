@@ -12,20 +12,34 @@ import (
 )
 
 var (
-	ErrInvalidListing = errors.New("invalid listing data")
+	ErrInvalidListing  = errors.New("invalid listing data")
+	ErrListingNotFound = errors.New("listing not found")
+	ErrForbidden       = errors.New("listing belongs to another user")
 )
 
 type Service interface {
 	Create(ctx context.Context, l *models.Listing) (*models.Listing, error)
-	List(ctx context.Context, filter storage.ListFilter) ([]*models.ListingWithAuthor, error)
+	Get(ctx context.Context, id int64) (*models.Listing, error)
+	Update(ctx context.Context, userID int64, role models.Role, id int64, upd storage.ListingUpdate) (*models.Listing, error)
+	Delete(ctx context.Context, userID int64, role models.Role, id int64) error
+	List(ctx context.Context, filter storage.ListFilter) (*Page, error)
+}
+
+// Page is one page of listings. NextCursor is empty on the last page, and
+// Total is only set when the filter asked for it.
+type Page struct {
+	Items      []*models.ListingWithAuthor
+	NextCursor string
+	Total      *int
 }
 
 type service struct {
 	listingRepo storage.ListingRepository
+	metrics     Metrics
 }
 
-func New(listingRepo storage.ListingRepository) Service {
-	return &service{listingRepo: listingRepo}
+func New(listingRepo storage.ListingRepository, metrics Metrics) Service {
+	return &service{listingRepo: listingRepo, metrics: metrics}
 }
 
 func (s *service) Create(ctx context.Context, l *models.Listing) (*models.Listing, error) {
@@ -45,21 +59,155 @@ func (s *service) Create(ctx context.Context, l *models.Listing) (*models.Listin
 		return nil, err
 	}
 
+	s.metrics.ListingCreated(created.Price)
 	log.Info("listing created successfully", slog.Int64("listing_id", created.ID))
 	return created, nil
 }
 
-func (s *service) List(ctx context.Context, filter storage.ListFilter) ([]*models.ListingWithAuthor, error) {
+func (s *service) Get(ctx context.Context, id int64) (*models.Listing, error) {
+	log := logger.
+		FromContext(ctx).
+		With("component", "service", "method", "GetListing", "listing_id", id)
+
+	l, err := s.listingRepo.GetListingByID(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Warn("listing not found")
+		return nil, ErrListingNotFound
+	}
+	if err != nil {
+		log.Error("failed to fetch listing", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	log.Debug("listing found")
+	return l, nil
+}
+
+func (s *service) Update(ctx context.Context, userID int64, role models.Role, id int64, upd storage.ListingUpdate) (*models.Listing, error) {
+	log := logger.
+		FromContext(ctx).
+		With("component", "service", "method", "UpdateListing", "listing_id", id, "user_id", userID, "role", role)
+
+	if !validUpdate(upd) {
+		log.Warn("invalid listing update", slog.Any("update", upd))
+		return nil, ErrInvalidListing
+	}
+
+	if _, err := s.authorize(ctx, userID, role, id); err != nil {
+		log.Warn("update rejected", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	updated, err := s.listingRepo.UpdateListing(ctx, id, upd)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Warn("listing disappeared before update")
+		return nil, ErrListingNotFound
+	}
+	if err != nil {
+		log.Error("failed to update listing", slog.String("err", err.Error()))
+		return nil, err
+	}
+
+	log.Info("listing updated successfully")
+	return updated, nil
+}
+
+func (s *service) Delete(ctx context.Context, userID int64, role models.Role, id int64) error {
+	log := logger.
+		FromContext(ctx).
+		With("component", "service", "method", "DeleteListing", "listing_id", id, "user_id", userID, "role", role)
+
+	if _, err := s.authorize(ctx, userID, role, id); err != nil {
+		log.Warn("delete rejected", slog.String("err", err.Error()))
+		return err
+	}
+
+	err := s.listingRepo.DeleteListing(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Warn("listing disappeared before delete")
+		return ErrListingNotFound
+	}
+	if err != nil {
+		log.Error("failed to delete listing", slog.String("err", err.Error()))
+		return err
+	}
+
+	log.Info("listing deleted successfully")
+	return nil
+}
+
+// authorize loads the listing and makes sure the user is allowed to modify it:
+// owners can touch their own listings, moderators and admins any listing.
+func (s *service) authorize(ctx context.Context, userID int64, role models.Role, id int64) (*models.Listing, error) {
+	l, err := s.listingRepo.GetListingByID(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrListingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if l.UserID != userID && !role.CanModerate() {
+		return nil, ErrForbidden
+	}
+	return l, nil
+}
+
+func validUpdate(upd storage.ListingUpdate) bool {
+	if upd.Title == nil && upd.Description == nil && upd.ImageURL == nil && upd.Price == nil {
+		return false
+	}
+	if upd.Title != nil && (strings.TrimSpace(*upd.Title) == "" || len(*upd.Title) > 100) {
+		return false
+	}
+	if upd.Description != nil && len(*upd.Description) > 1000 {
+		return false
+	}
+	if upd.Price != nil && *upd.Price <= 0 {
+		return false
+	}
+	return true
+}
+
+func (s *service) List(ctx context.Context, filter storage.ListFilter) (*Page, error) {
 	log := logger.
 		FromContext(ctx).
 		With("component", "service", "method", "List")
 
-	listings, err := s.listingRepo.ListListings(ctx, filter)
+	if filter.After != nil && !filter.After.Matches(filter) {
+		log.Warn("cursor does not match sort order", slog.Any("cursor", filter.After))
+		return nil, storage.ErrInvalidCursor
+	}
+
+	// Fetch one extra row to find out whether another page follows.
+	limit := filter.Limit
+	if limit > 0 {
+		filter.Limit = limit + 1
+	}
+
+	var (
+		listings []*models.ListingWithAuthor
+		total    int
+		err      error
+	)
+	if filter.WithTotal {
+		listings, total, err = s.listingRepo.ListListingsWithTotal(ctx, filter)
+	} else {
+		listings, err = s.listingRepo.ListListings(ctx, filter)
+	}
 	if err != nil {
 		log.Error("failed to fetch listings", slog.String("err", err.Error()), slog.Any("filter", filter))
 		return nil, err
 	}
 
-	log.Debug("listings fetched", slog.Int("count", len(listings)))
-	return listings, nil
+	page := &Page{Items: listings}
+	if filter.WithTotal {
+		page.Total = &total
+	}
+	if limit > 0 && len(listings) > limit {
+		page.Items = listings[:limit]
+		page.NextCursor = storage.CursorAfter(filter, page.Items[limit-1]).Encode()
+	}
+
+	log.Debug("listings fetched", slog.Int("count", len(page.Items)), slog.Bool("has_more", page.NextCursor != ""))
+	return page, nil
 }
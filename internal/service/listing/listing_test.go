@@ -26,6 +26,21 @@ func (m *mockRepo) CreateListing(ctx context.Context, l *models.Listing) (*model
 	return args.Get(0).(*models.Listing), args.Error(1)
 }
 
+func (m *mockRepo) GetListingByID(ctx context.Context, id int64) (*models.Listing, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.Listing), args.Error(1)
+}
+
+func (m *mockRepo) UpdateListing(ctx context.Context, id int64, upd storage.ListingUpdate) (*models.Listing, error) {
+	args := m.Called(ctx, id, upd)
+	return args.Get(0).(*models.Listing), args.Error(1)
+}
+
+func (m *mockRepo) DeleteListing(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 func (m *mockRepo) ListListings(ctx context.Context, filter storage.ListFilter) ([]*models.ListingWithAuthor, error) {
 	args := m.Called(ctx, filter)
 	return args.Get(0).([]*models.ListingWithAuthor), args.Error(1)
@@ -33,9 +48,14 @@ func (m *mockRepo) ListListings(ctx context.Context, filter storage.ListFilter)
 
 // --- Tests ---
 
+func (m *mockRepo) ListListingsWithTotal(ctx context.Context, filter storage.ListFilter) ([]*models.ListingWithAuthor, int, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*models.ListingWithAuthor), args.Int(1), args.Error(2)
+}
+
 func TestCreate_Success(t *testing.T) {
 	repo := new(mockRepo)
-	svc := listing.New(repo)
+	svc := listing.New(repo, listing.NopMetrics{})
 
 	input := &models.Listing{
 		Title:       "T-shirt",
@@ -65,7 +85,7 @@ func TestCreate_Success(t *testing.T) {
 
 func TestCreate_InvalidInput(t *testing.T) {
 	repo := new(mockRepo)
-	svc := listing.New(repo)
+	svc := listing.New(repo, listing.NopMetrics{})
 
 	invalidInputs := []*models.Listing{
 		{Title: "", Description: "Valid", Price: 1000, UserID: 1},
@@ -85,7 +105,7 @@ func TestCreate_InvalidInput(t *testing.T) {
 
 func TestCreate_RepoError(t *testing.T) {
 	repo := new(mockRepo)
-	svc := listing.New(repo)
+	svc := listing.New(repo, listing.NopMetrics{})
 
 	input := &models.Listing{
 		Title:       "Phone",
@@ -106,33 +126,90 @@ func TestCreate_RepoError(t *testing.T) {
 
 func TestList_Success(t *testing.T) {
 	repo := new(mockRepo)
-	svc := listing.New(repo)
+	svc := listing.New(repo, listing.NopMetrics{})
 
 	filter := storage.ListFilter{Limit: 10, Offset: 0}
 	expected := []*models.ListingWithAuthor{
 		{ID: 1, Title: "Shirt", AuthorLogin: "alice", Price: 1000},
 	}
 
-	repo.On("ListListings", mock.Anything, filter).Return(expected, nil)
+	repo.On("ListListings", mock.Anything, storage.ListFilter{Limit: 11}).Return(expected, nil)
 
 	ctx := context.Background()
 	res, err := svc.List(ctx, filter)
 
 	assert.NoError(t, err)
-	assert.Equal(t, expected, res)
+	assert.Equal(t, expected, res.Items)
+	assert.Empty(t, res.NextCursor)
 	repo.AssertExpectations(t)
 }
 
+func TestList_NextCursor(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	filter := storage.ListFilter{Limit: 2, SortBy: "price", SortOrder: "asc"}
+	rows := []*models.ListingWithAuthor{
+		{ID: 5, Price: 100},
+		{ID: 2, Price: 250.5},
+		{ID: 8, Price: 300},
+	}
+
+	repo.On("ListListings", mock.Anything, mock.MatchedBy(func(f storage.ListFilter) bool {
+		return f.Limit == 3
+	})).Return(rows, nil)
+
+	res, err := svc.List(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Len(t, res.Items, 2)
+
+	cursor, err := storage.DecodeCursor(res.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, &storage.Cursor{SortBy: "price", SortOrder: "asc", Value: "250.5", ID: 2}, cursor)
+}
+
+func TestList_WithTotal(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	filter := storage.ListFilter{Limit: 10, WithTotal: true}
+	expected := []*models.ListingWithAuthor{{ID: 1}}
+
+	repo.On("ListListingsWithTotal", mock.Anything, storage.ListFilter{Limit: 11, WithTotal: true}).Return(expected, 31, nil)
+
+	res, err := svc.List(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, res.Items)
+	assert.NotNil(t, res.Total)
+	assert.Equal(t, 31, *res.Total)
+	repo.AssertNotCalled(t, "ListListings", mock.Anything, mock.Anything)
+}
+
+func TestList_CursorSortMismatch(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	filter := storage.ListFilter{
+		Limit:  10,
+		SortBy: "price",
+		After:  &storage.Cursor{SortBy: "created_at", SortOrder: "desc", Value: "2025-01-01T00:00:00Z", ID: 3},
+	}
+
+	_, err := svc.List(context.Background(), filter)
+	assert.ErrorIs(t, err, storage.ErrInvalidCursor)
+	repo.AssertNotCalled(t, "ListListings", mock.Anything, mock.Anything)
+}
+
 func TestList_RepoError(t *testing.T) {
 	repo := new(mockRepo)
-	svc := listing.New(repo)
+	svc := listing.New(repo, listing.NopMetrics{})
 
 	filter := storage.ListFilter{
 		Limit:  10,
 		Offset: 0,
 	}
 
-	repo.On("ListListings", mock.Anything, filter).Return(([]*models.ListingWithAuthor)(nil), errors.New("query failed"))
+	repo.On("ListListings", mock.Anything, mock.Anything).Return(([]*models.ListingWithAuthor)(nil), errors.New("query failed"))
 
 	ctx := context.Background()
 	_, err := svc.List(ctx, filter)
@@ -141,3 +218,162 @@ func TestList_RepoError(t *testing.T) {
 	assert.Contains(t, err.Error(), "query failed")
 	repo.AssertExpectations(t)
 }
+
+func TestGet_Success(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	expected := &models.Listing{ID: 7, Title: "Lamp", UserID: 3}
+	repo.On("GetListingByID", mock.Anything, int64(7)).Return(expected, nil)
+
+	res, err := svc.Get(context.Background(), 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, res)
+	repo.AssertExpectations(t)
+}
+
+func TestGet_NotFound(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	repo.On("GetListingByID", mock.Anything, int64(7)).Return((*models.Listing)(nil), storage.ErrNotFound)
+
+	_, err := svc.Get(context.Background(), 7)
+	assert.ErrorIs(t, err, listing.ErrListingNotFound)
+}
+
+func TestUpdate_Success(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	title := "New title"
+	upd := storage.ListingUpdate{Title: &title}
+	updated := &models.Listing{ID: 7, Title: title, UserID: 3}
+
+	repo.On("GetListingByID", mock.Anything, int64(7)).Return(&models.Listing{ID: 7, UserID: 3}, nil)
+	repo.On("UpdateListing", mock.Anything, int64(7), upd).Return(updated, nil)
+
+	res, err := svc.Update(context.Background(), 3, models.RoleUser, 7, upd)
+
+	assert.NoError(t, err)
+	assert.Equal(t, updated, res)
+	repo.AssertExpectations(t)
+}
+
+func TestUpdate_Forbidden(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	title := "New title"
+	repo.On("GetListingByID", mock.Anything, int64(7)).Return(&models.Listing{ID: 7, UserID: 3}, nil)
+
+	_, err := svc.Update(context.Background(), 4, models.RoleUser, 7, storage.ListingUpdate{Title: &title})
+
+	assert.ErrorIs(t, err, listing.ErrForbidden)
+	repo.AssertNotCalled(t, "UpdateListing", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdate_ModeratorCanEditAnyListing(t *testing.T) {
+	for _, role := range []models.Role{models.RoleModerator, models.RoleAdmin} {
+		repo := new(mockRepo)
+		svc := listing.New(repo, listing.NopMetrics{})
+
+		title := "Moderated title"
+		upd := storage.ListingUpdate{Title: &title}
+		updated := &models.Listing{ID: 7, Title: title, UserID: 3}
+
+		repo.On("GetListingByID", mock.Anything, int64(7)).Return(&models.Listing{ID: 7, UserID: 3}, nil)
+		repo.On("UpdateListing", mock.Anything, int64(7), upd).Return(updated, nil)
+
+		res, err := svc.Update(context.Background(), 4, role, 7, upd)
+
+		assert.NoError(t, err)
+		assert.Equal(t, updated, res)
+		repo.AssertExpectations(t)
+	}
+}
+
+func TestUpdate_NotFound(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	price := 10.0
+	repo.On("GetListingByID", mock.Anything, int64(7)).Return((*models.Listing)(nil), storage.ErrNotFound)
+
+	_, err := svc.Update(context.Background(), 3, models.RoleUser, 7, storage.ListingUpdate{Price: &price})
+	assert.ErrorIs(t, err, listing.ErrListingNotFound)
+}
+
+func TestUpdate_InvalidInput(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	empty := " "
+	zero := 0.0
+	longDesc := strings.Repeat("a", 1001)
+
+	invalidUpdates := []storage.ListingUpdate{
+		{},
+		{Title: &empty},
+		{Description: &longDesc},
+		{Price: &zero},
+	}
+
+	for _, upd := range invalidUpdates {
+		_, err := svc.Update(context.Background(), 3, models.RoleUser, 7, upd)
+		assert.ErrorIs(t, err, listing.ErrInvalidListing)
+	}
+	repo.AssertNotCalled(t, "GetListingByID", mock.Anything, mock.Anything)
+}
+
+func TestDelete_Success(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	repo.On("GetListingByID", mock.Anything, int64(7)).Return(&models.Listing{ID: 7, UserID: 3}, nil)
+	repo.On("DeleteListing", mock.Anything, int64(7)).Return(nil)
+
+	err := svc.Delete(context.Background(), 3, models.RoleUser, 7)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestDelete_Forbidden(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	repo.On("GetListingByID", mock.Anything, int64(7)).Return(&models.Listing{ID: 7, UserID: 3}, nil)
+
+	err := svc.Delete(context.Background(), 4, models.RoleUser, 7)
+
+	assert.ErrorIs(t, err, listing.ErrForbidden)
+	repo.AssertNotCalled(t, "DeleteListing", mock.Anything, mock.Anything)
+}
+
+func TestDelete_AdminCanRemoveAnyListing(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	repo.On("GetListingByID", mock.Anything, int64(7)).Return(&models.Listing{ID: 7, UserID: 3}, nil)
+	repo.On("DeleteListing", mock.Anything, int64(7)).Return(nil)
+
+	err := svc.Delete(context.Background(), 4, models.RoleAdmin, 7)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestDelete_RepoError(t *testing.T) {
+	repo := new(mockRepo)
+	svc := listing.New(repo, listing.NopMetrics{})
+
+	repo.On("GetListingByID", mock.Anything, int64(7)).Return(&models.Listing{ID: 7, UserID: 3}, nil)
+	repo.On("DeleteListing", mock.Anything, int64(7)).Return(errors.New("delete failed"))
+
+	err := svc.Delete(context.Background(), 3, models.RoleUser, 7)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "delete failed")
+}
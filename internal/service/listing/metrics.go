@@ -0,0 +1,11 @@
+package listing
+
+// Metrics counts listing events for dashboards and alerts.
+type Metrics interface {
+	ListingCreated(price float64)
+}
+
+// NopMetrics discards everything.
+type NopMetrics struct{}
+
+func (NopMetrics) ListingCreated(float64) {}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/justcgh9/vk-internship-application/internal/models"
+)
+
+const (
+	SortByCreatedAt = "created_at"
+	SortByPrice     = "price"
+	SortByRelevance = "relevance"
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a keyset pagination position: the sort key of the last listing
+// on the previous page plus its id as a tiebreaker. Clients only ever see it
+// in its encoded, opaque form.
+type Cursor struct {
+	SortBy    string `json:"s"`
+	SortOrder string `json:"o"`
+	Value     string `json:"v"`
+	ID        int64  `json:"i"`
+}
+
+// CursorAfter returns the position right after l in a listing sorted as
+// described by filter.
+func CursorAfter(filter ListFilter, l *models.ListingWithAuthor) *Cursor {
+	sortBy, sortOrder := filter.Sort()
+
+	c := &Cursor{SortBy: sortBy, SortOrder: sortOrder, ID: l.ID}
+	switch sortBy {
+	case SortByPrice:
+		c.Value = strconv.FormatFloat(l.Price, 'f', -1, 64)
+	case SortByRelevance:
+		c.Value = strconv.FormatFloat(float64(l.Relevance), 'g', -1, 32)
+	default:
+		c.Value = l.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return c
+}
+
+func (c *Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor produced by Encode. Anything that was not
+// produced by Encode yields ErrInvalidCursor.
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	switch c.SortBy {
+	case SortByCreatedAt:
+		_, err = time.Parse(time.RFC3339Nano, c.Value)
+	case SortByPrice:
+		_, err = strconv.ParseFloat(c.Value, 64)
+	case SortByRelevance:
+		_, err = strconv.ParseFloat(c.Value, 32)
+	default:
+		err = ErrInvalidCursor
+	}
+	if err != nil || (c.SortOrder != SortAsc && c.SortOrder != SortDesc) {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// Matches reports whether the cursor was issued for the same ordering as
+// filter; a cursor is meaningless under any other ordering.
+func (c *Cursor) Matches(filter ListFilter) bool {
+	sortBy, sortOrder := filter.Sort()
+	return c.SortBy == sortBy && c.SortOrder == sortOrder
+}
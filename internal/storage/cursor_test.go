@@ -0,0 +1,72 @@
+package storage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	createdAt := time.Date(2025, 3, 14, 15, 9, 26, 535897000, time.UTC)
+	l := &models.ListingWithAuthor{ID: 7, Price: 99.95, CreatedAt: createdAt}
+
+	byDate := storage.CursorAfter(storage.ListFilter{}, l)
+	decoded, err := storage.DecodeCursor(byDate.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, &storage.Cursor{SortBy: "created_at", SortOrder: "desc", Value: "2025-03-14T15:09:26.535897Z", ID: 7}, decoded)
+
+	byPrice := storage.CursorAfter(storage.ListFilter{SortBy: "price", SortOrder: "asc"}, l)
+	decoded, err = storage.DecodeCursor(byPrice.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, &storage.Cursor{SortBy: "price", SortOrder: "asc", Value: "99.95", ID: 7}, decoded)
+
+	l.Relevance = 0.0607927
+	byRelevance := storage.CursorAfter(storage.ListFilter{Query: "bike"}, l)
+	decoded, err = storage.DecodeCursor(byRelevance.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, &storage.Cursor{SortBy: "relevance", SortOrder: "desc", Value: "0.0607927", ID: 7}, decoded)
+}
+
+func TestListFilter_Sort(t *testing.T) {
+	for _, tc := range []struct {
+		filter storage.ListFilter
+		by     string
+		order  string
+	}{
+		{storage.ListFilter{}, "created_at", "desc"},
+		{storage.ListFilter{SortBy: "price", SortOrder: "asc"}, "price", "asc"},
+		{storage.ListFilter{SortBy: "relevance"}, "created_at", "desc"},
+		{storage.ListFilter{Query: "bike"}, "relevance", "desc"},
+		{storage.ListFilter{Query: "bike", SortBy: "created_at", SortOrder: "asc"}, "created_at", "asc"},
+		{storage.ListFilter{Query: "bike", SortBy: "bogus"}, "relevance", "desc"},
+	} {
+		by, order := tc.filter.Sort()
+		assert.Equal(t, tc.by, by, "%+v", tc.filter)
+		assert.Equal(t, tc.order, order, "%+v", tc.filter)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		"not base64!",
+		"bm90IGpzb24",
+		(&storage.Cursor{SortBy: "title", SortOrder: "asc", Value: "x", ID: 1}).Encode(),
+		(&storage.Cursor{SortBy: "price", SortOrder: "up", Value: "10", ID: 1}).Encode(),
+		(&storage.Cursor{SortBy: "price", SortOrder: "asc", Value: "1; DROP TABLE", ID: 1}).Encode(),
+		(&storage.Cursor{SortBy: "created_at", SortOrder: "desc", Value: "yesterday", ID: 1}).Encode(),
+	} {
+		_, err := storage.DecodeCursor(raw)
+		assert.ErrorIs(t, err, storage.ErrInvalidCursor, raw)
+	}
+}
+
+func TestCursor_Matches(t *testing.T) {
+	c := &storage.Cursor{SortBy: "created_at", SortOrder: "desc"}
+	assert.True(t, c.Matches(storage.ListFilter{}))
+	assert.False(t, c.Matches(storage.ListFilter{SortBy: "price"}))
+	assert.False(t, c.Matches(storage.ListFilter{SortOrder: "asc"}))
+}
@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"cmp"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+)
+
+// row is a listing that matched a filter, together with what it is sorted by.
+type row struct {
+	listing *models.Listing
+	author  *models.User
+	rank    float32
+}
+
+// before reports whether r comes before other in the given ordering. The id
+// breaks ties in the same direction, like ORDER BY <key>, l.id does.
+func (r row) before(other row, sortBy, sortOrder string) bool {
+	var c int
+	switch sortBy {
+	case storage.SortByPrice:
+		c = cmp.Compare(r.listing.Price, other.listing.Price)
+	case storage.SortByRelevance:
+		c = cmp.Compare(r.rank, other.rank)
+	default:
+		c = r.listing.CreatedAt.Compare(other.listing.CreatedAt)
+	}
+	if c == 0 {
+		c = cmp.Compare(r.listing.ID, other.listing.ID)
+	}
+	if sortOrder == storage.SortAsc {
+		return c < 0
+	}
+	return c > 0
+}
+
+// cursorRow turns a cursor back into the position of the row it was taken
+// from.
+func cursorRow(c *storage.Cursor) (row, error) {
+	r := row{listing: &models.Listing{ID: c.ID}}
+	switch c.SortBy {
+	case storage.SortByPrice:
+		price, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return row{}, storage.ErrInvalidCursor
+		}
+		r.listing.Price = price
+	case storage.SortByRelevance:
+		rank, err := strconv.ParseFloat(c.Value, 32)
+		if err != nil {
+			return row{}, storage.ErrInvalidCursor
+		}
+		r.rank = float32(rank)
+	default:
+		createdAt, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return row{}, storage.ErrInvalidCursor
+		}
+		r.listing.CreatedAt = createdAt
+	}
+	return r, nil
+}
+
+// Weights of title and description matches, the defaults ts_rank uses for
+// the A and B labels.
+const (
+	titleWeight       = 1.0
+	descriptionWeight = 0.4
+)
+
+// search approximates websearch_to_tsquery: every word has to occur in the
+// title or the description and words prefixed with '-' must not. There is
+// no stemming; a query word matches any word it is a prefix of, so "bike"
+// finds "bikes" but not the other way round.
+type search struct {
+	include []string
+	exclude []string
+}
+
+func parseSearch(query string) search {
+	var s search
+	for _, field := range strings.Fields(strings.ToLower(query)) {
+		negated := strings.HasPrefix(field, "-")
+		for _, word := range words(field) {
+			if negated {
+				s.exclude = append(s.exclude, word)
+			} else {
+				s.include = append(s.include, word)
+			}
+		}
+	}
+	return s
+}
+
+// rank reports whether l matches and how relevant it is.
+func (s search) rank(l *models.Listing) (float32, bool) {
+	if len(s.include) == 0 {
+		return 0, false
+	}
+
+	title, description := words(strings.ToLower(l.Title)), words(strings.ToLower(l.Description))
+	for _, term := range s.exclude {
+		if count(title, term)+count(description, term) > 0 {
+			return 0, false
+		}
+	}
+
+	var rank float32
+	for _, term := range s.include {
+		t, d := count(title, term), count(description, term)
+		if t+d == 0 {
+			return 0, false
+		}
+		rank += titleWeight*float32(t) + descriptionWeight*float32(d)
+	}
+	return rank, true
+}
+
+// highlight renders the same text ts_headline is given, HTML-escaped, with
+// matched words wrapped in <mark>. Unlike ts_headline it does not cut the
+// text into fragments.
+func (s search) highlight(l *models.Listing) string {
+	text := l.Title + " — " + l.Description
+
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		word := text[start:end]
+		if s.matches(strings.ToLower(word)) {
+			b.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		start = -1
+	}
+	for i, r := range text {
+		switch {
+		case isWordRune(r):
+			if start < 0 {
+				start = i
+			}
+		default:
+			if start >= 0 {
+				flush(i)
+			}
+			b.WriteString(html.EscapeString(string(r)))
+		}
+	}
+	if start >= 0 {
+		flush(len(text))
+	}
+	return b.String()
+}
+
+func (s search) matches(word string) bool {
+	for _, term := range s.include {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return !isWordRune(r) })
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func count(words []string, term string) int {
+	n := 0
+	for _, w := range words {
+		if strings.HasPrefix(w, term) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+)
+
+// RevocationStore is a process-local storage.RevocationStore. It is only
+// suitable for a single replica: revocations are lost on restart. Token
+// versions exist only for the users of users, as they do for the
+// token_version column in Postgres, so that the tokens of a deleted user stop
+// verifying.
+type RevocationStore struct {
+	users storage.UserRepository
+
+	mu       sync.RWMutex
+	revoked  map[string]time.Time
+	versions map[int64]int
+}
+
+func NewRevocationStore(users storage.UserRepository) *RevocationStore {
+	return &RevocationStore{
+		users:    users,
+		revoked:  make(map[string]time.Time),
+		versions: make(map[int64]int),
+	}
+}
+
+func (s *RevocationStore) RevokeToken(_ context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, exp := range s.revoked {
+		if exp.Before(now) {
+			delete(s.revoked, id)
+		}
+	}
+
+	s.revoked[jti] = expiresAt
+	return nil
+}
+
+func (s *RevocationStore) IsTokenRevoked(_ context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.revoked[jti]
+	return ok, nil
+}
+
+func (s *RevocationStore) GetTokenVersion(ctx context.Context, userID int64) (int, error) {
+	if _, err := s.users.GetUserByID(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.versions[userID], nil
+}
+
+func (s *RevocationStore) IncrementTokenVersion(ctx context.Context, userID int64) (int, error) {
+	if _, err := s.users.GetUserByID(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.versions[userID]++
+	return s.versions[userID], nil
+}
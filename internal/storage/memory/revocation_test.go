@@ -0,0 +1,18 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/justcgh9/vk-internship-application/internal/storage/memory"
+	"github.com/justcgh9/vk-internship-application/internal/storage/storagetest"
+)
+
+func TestRevocationStore_Conformance(t *testing.T) {
+	storagetest.RunRevocation(t, func(t *testing.T) storagetest.RevocationBackend {
+		users := memory.NewStorage()
+		return struct {
+			*memory.Storage
+			*memory.RevocationStore
+		}{users, memory.NewRevocationStore(users)}
+	})
+}
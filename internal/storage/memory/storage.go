@@ -0,0 +1,407 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+)
+
+// Storage keeps users, listings and refresh tokens in process memory. It
+// follows the semantics of postgres.Storage, the constraints of the schema
+// included, and is meant for tests and for running the app locally
+// without a database. Everything is lost on restart.
+type Storage struct {
+	mu rwLocker
+
+	users     map[int64]*models.User
+	usernames map[string]int64
+	listings  map[int64]*models.Listing
+	tokens    map[string]*models.RefreshToken
+
+	lastUserID    int64
+	lastListingID int64
+	lastTokenID   int64
+
+	// undo is set inside a transaction.
+	undo *undoLog
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		mu:        &sync.RWMutex{},
+		users:     make(map[int64]*models.User),
+		usernames: make(map[string]int64),
+		listings:  make(map[int64]*models.Listing),
+		tokens:    make(map[string]*models.RefreshToken),
+	}
+}
+
+// now mimics a Postgres timestamp: UTC with microsecond precision, so that
+// values survive a round trip through a cursor unchanged.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// roundPrice mimics the NUMERIC(10, 2) price column.
+func roundPrice(p float64) float64 {
+	return math.Round(p*100) / 100
+}
+
+// Limits of the schema in migrations/.
+const (
+	maxUsernameLength    = 32
+	minTitleLength       = 3
+	maxTitleLength       = 100
+	minDescriptionLength = 10
+	maxPrice             = 1e8
+)
+
+func validListing(l *models.Listing) bool {
+	title := utf8.RuneCountInString(l.Title)
+	price := roundPrice(l.Price)
+	return title >= minTitleLength && title <= maxTitleLength &&
+		utf8.RuneCountInString(l.Description) >= minDescriptionLength &&
+		price >= 0 && price < maxPrice
+}
+
+// --- UserRepository ---
+
+func (s *Storage) CreateUser(_ context.Context, username, passwordHash string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if utf8.RuneCountInString(username) > maxUsernameLength {
+		return nil, storage.ErrInvalid
+	}
+	if _, ok := s.usernames[username]; ok {
+		return nil, storage.ErrConflict
+	}
+
+	s.lastUserID++
+	u := &models.User{
+		ID:           s.lastUserID,
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         models.RoleUser,
+		CreatedAt:    now(),
+	}
+	s.users[u.ID] = u
+	s.usernames[username] = u.ID
+	s.undo.add(func() {
+		delete(s.users, u.ID)
+		delete(s.usernames, username)
+	})
+
+	out := *u
+	out.PasswordHash = ""
+	return &out, nil
+}
+
+func (s *Storage) GetUserByUsername(_ context.Context, username string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.usernames[username]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	u := *s.users[id]
+	return &u, nil
+}
+
+func (s *Storage) GetUserByID(_ context.Context, id int64) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	out := *u
+	return &out, nil
+}
+
+func (s *Storage) UpdateUserRole(_ context.Context, id int64, role models.Role) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	if !role.Valid() {
+		return nil, storage.ErrInvalid
+	}
+	prev := u.Role
+	u.Role = role
+	s.undo.add(func() { u.Role = prev })
+
+	out := *u
+	out.PasswordHash = ""
+	return &out, nil
+}
+
+// --- ListingRepository ---
+
+func (s *Storage) CreateListing(_ context.Context, l *models.Listing) (*models.Listing, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !validListing(l) {
+		return nil, storage.ErrInvalid
+	}
+	if _, ok := s.users[l.UserID]; !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	s.lastListingID++
+	l.ID = s.lastListingID
+	l.CreatedAt = now()
+
+	stored := *l
+	stored.Price = roundPrice(l.Price)
+	s.listings[l.ID] = &stored
+	s.undo.add(func() { delete(s.listings, stored.ID) })
+	return l, nil
+}
+
+func (s *Storage) GetListingByID(_ context.Context, id int64) (*models.Listing, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.listings[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	out := *l
+	return &out, nil
+}
+
+func (s *Storage) UpdateListing(_ context.Context, id int64, upd storage.ListingUpdate) (*models.Listing, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.listings[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	l := *stored
+	if upd.Title != nil {
+		l.Title = *upd.Title
+	}
+	if upd.Description != nil {
+		l.Description = *upd.Description
+	}
+	if upd.ImageURL != nil {
+		l.ImageURL = *upd.ImageURL
+	}
+	if upd.Price != nil {
+		l.Price = roundPrice(*upd.Price)
+	}
+	if !validListing(&l) {
+		return nil, storage.ErrInvalid
+	}
+	prev := *stored
+	*stored = l
+	s.undo.add(func() { *stored = prev })
+
+	out := l
+	return &out, nil
+}
+
+func (s *Storage) DeleteListing(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.listings[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	delete(s.listings, id)
+	s.undo.add(func() { s.listings[id] = l })
+	return nil
+}
+
+func (s *Storage) ListListings(ctx context.Context, filter storage.ListFilter) ([]*models.ListingWithAuthor, error) {
+	listings, _, err := s.ListListingsWithTotal(ctx, filter)
+	return listings, err
+}
+
+func (s *Storage) ListListingsWithTotal(_ context.Context, filter storage.ListFilter) ([]*models.ListingWithAuthor, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	search := parseSearch(filter.Query)
+
+	var rows []row
+	for _, l := range s.listings {
+		author := s.users[l.UserID]
+		if !matchesFilter(l, author, filter) {
+			continue
+		}
+		r := row{listing: l, author: author}
+		if filter.Query != "" {
+			var ok bool
+			if r.rank, ok = search.rank(l); !ok {
+				continue
+			}
+		}
+		rows = append(rows, r)
+	}
+	total := len(rows)
+
+	sortBy, sortOrder := filter.Sort()
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].before(rows[j], sortBy, sortOrder)
+	})
+
+	// Keyset pagination
+	if filter.After != nil {
+		after, err := cursorRow(filter.After)
+		if err != nil {
+			return nil, 0, err
+		}
+		i := sort.Search(len(rows), func(i int) bool {
+			return after.before(rows[i], sortBy, sortOrder)
+		})
+		rows = rows[i:]
+	} else {
+		rows = rows[min(filter.Offset, len(rows)):]
+	}
+	rows = rows[:min(max(filter.Limit, 0), len(rows))]
+
+	var listings []*models.ListingWithAuthor
+	for _, r := range rows {
+		l := &models.ListingWithAuthor{
+			ID:          r.listing.ID,
+			Title:       r.listing.Title,
+			Description: r.listing.Description,
+			ImageURL:    r.listing.ImageURL,
+			Price:       r.listing.Price,
+			AuthorLogin: r.author.Username,
+			IsOwned:     filter.ViewerID != nil && *filter.ViewerID == r.listing.UserID,
+			CreatedAt:   r.listing.CreatedAt,
+		}
+		if filter.Query != "" {
+			l.Relevance = r.rank
+			l.Highlight = search.highlight(r.listing)
+		}
+		listings = append(listings, l)
+	}
+	return listings, total, nil
+}
+
+func matchesFilter(l *models.Listing, author *models.User, filter storage.ListFilter) bool {
+	switch {
+	case filter.PriceMin != nil && l.Price < *filter.PriceMin:
+		return false
+	case filter.PriceMax != nil && l.Price > *filter.PriceMax:
+		return false
+	case filter.AuthorID != nil && l.UserID != *filter.AuthorID:
+		return false
+	case filter.AuthorLogin != "" && author.Username != filter.AuthorLogin:
+		return false
+	case filter.OnlyMine && filter.ViewerID != nil && l.UserID != *filter.ViewerID:
+		return false
+	case filter.CreatedAfter != nil && l.CreatedAt.Before(*filter.CreatedAfter):
+		return false
+	case filter.CreatedBefore != nil && !l.CreatedAt.Before(*filter.CreatedBefore):
+		return false
+	case filter.HasImage != nil && (l.ImageURL != "") != *filter.HasImage:
+		return false
+	}
+	return true
+}
+
+// --- RefreshTokenRepository ---
+
+func (s *Storage) CreateRefreshToken(_ context.Context, t *models.RefreshToken) (*models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[t.UserID]; !ok {
+		return nil, storage.ErrNotFound
+	}
+	if _, ok := s.tokens[t.TokenHash]; ok {
+		return nil, storage.ErrConflict
+	}
+
+	s.lastTokenID++
+	t.ID = s.lastTokenID
+	t.CreatedAt = now()
+
+	stored := *t
+	s.tokens[t.TokenHash] = &stored
+	s.undo.add(func() { delete(s.tokens, stored.TokenHash) })
+	return t, nil
+}
+
+func (s *Storage) GetRefreshToken(_ context.Context, tokenHash string) (*models.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tokens[tokenHash]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	out := *t
+	return &out, nil
+}
+
+func (s *Storage) RotateRefreshToken(_ context.Context, oldHash string, next *models.RefreshToken) (*models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.tokens[oldHash]
+	if !ok || old.RevokedAt != nil || !old.ExpiresAt.After(time.Now()) {
+		return nil, storage.ErrNotFound
+	}
+	if _, ok := s.tokens[next.TokenHash]; ok {
+		return nil, storage.ErrConflict
+	}
+
+	revokedAt := now()
+	old.RevokedAt = &revokedAt
+	s.undo.add(func() { old.RevokedAt = nil })
+
+	s.lastTokenID++
+	next.ID = s.lastTokenID
+	next.UserID = old.UserID
+	next.FamilyID = old.FamilyID
+	next.CreatedAt = revokedAt
+
+	stored := *next
+	s.tokens[next.TokenHash] = &stored
+	s.undo.add(func() { delete(s.tokens, stored.TokenHash) })
+	return next, nil
+}
+
+func (s *Storage) RevokeRefreshTokenFamily(_ context.Context, familyID string) error {
+	s.revokeTokens(func(t *models.RefreshToken) bool { return t.FamilyID == familyID })
+	return nil
+}
+
+func (s *Storage) RevokeUserRefreshTokens(_ context.Context, userID int64) error {
+	s.revokeTokens(func(t *models.RefreshToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (s *Storage) revokeTokens(match func(*models.RefreshToken) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revokedAt := now()
+	for _, t := range s.tokens {
+		if t.RevokedAt == nil && match(t) {
+			t.RevokedAt = &revokedAt
+			s.undo.add(func() { t.RevokedAt = nil })
+		}
+	}
+}
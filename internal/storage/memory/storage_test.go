@@ -0,0 +1,109 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+	"github.com/justcgh9/vk-internship-application/internal/storage/memory"
+	"github.com/justcgh9/vk-internship-application/internal/storage/storagetest"
+)
+
+func TestStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Backend {
+		return memory.NewStorage()
+	})
+}
+
+func TestStorage_RotateRefreshToken(t *testing.T) {
+	store := memory.NewStorage()
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "alice", "hash")
+	assert.NoError(t, err)
+
+	_, err = store.CreateRefreshToken(ctx, &models.RefreshToken{
+		TokenHash: "old",
+		UserID:    user.ID,
+		FamilyID:  "family",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	next, err := store.RotateRefreshToken(ctx, "old", &models.RefreshToken{TokenHash: "new", ExpiresAt: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, next.UserID)
+	assert.Equal(t, "family", next.FamilyID)
+
+	_, err = store.RotateRefreshToken(ctx, "old", &models.RefreshToken{TokenHash: "newer", ExpiresAt: time.Now().Add(time.Hour)})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	assert.NoError(t, store.RevokeRefreshTokenFamily(ctx, "family"))
+	stored, err := store.GetRefreshToken(ctx, "new")
+	assert.NoError(t, err)
+	assert.NotNil(t, stored.RevokedAt)
+}
+
+func TestStorage_TxRollbackRestoresChanges(t *testing.T) {
+	store := memory.NewStorage()
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "alice", "hash")
+	assert.NoError(t, err)
+	updated, err := store.CreateListing(ctx, &models.Listing{
+		Title: "Road bike", Description: "Barely used road bike", Price: 100, UserID: user.ID,
+	})
+	assert.NoError(t, err)
+	deleted, err := store.CreateListing(ctx, &models.Listing{
+		Title: "Helmet", Description: "Helmet in good shape", Price: 20, UserID: user.ID,
+	})
+	assert.NoError(t, err)
+	_, err = store.CreateRefreshToken(ctx, &models.RefreshToken{
+		TokenHash: "old", UserID: user.ID, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	title := "Racing bike"
+	for _, panics := range []bool{false, true} {
+		run := func() error {
+			return store.WithTx(ctx, func(tx storage.Repos) error {
+				if _, err := tx.Listings.UpdateListing(ctx, updated.ID, storage.ListingUpdate{Title: &title}); err != nil {
+					return err
+				}
+				if err := tx.Listings.DeleteListing(ctx, deleted.ID); err != nil {
+					return err
+				}
+				if _, err := tx.RefreshTokens.RotateRefreshToken(ctx, "old", &models.RefreshToken{
+					TokenHash: "next", ExpiresAt: time.Now().Add(time.Hour),
+				}); err != nil {
+					return err
+				}
+				if panics {
+					panic("abort")
+				}
+				return errors.New("abort")
+			})
+		}
+		if panics {
+			assert.Panics(t, func() { _ = run() })
+		} else {
+			assert.Error(t, run())
+		}
+
+		l, err := store.GetListingByID(ctx, updated.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Road bike", l.Title)
+		_, err = store.GetListingByID(ctx, deleted.ID)
+		assert.NoError(t, err)
+		old, err := store.GetRefreshToken(ctx, "old")
+		assert.NoError(t, err)
+		assert.Nil(t, old.RevokedAt)
+		_, err = store.GetRefreshToken(ctx, "next")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	}
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+)
+
+type rwLocker interface {
+	Lock()
+	Unlock()
+	RLock()
+	RUnlock()
+}
+
+// noLock guards the private copy a transaction works on, which the
+// transaction already holds the lock for.
+type noLock struct{}
+
+func (noLock) Lock()    {}
+func (noLock) Unlock()  {}
+func (noLock) RLock()   {}
+func (noLock) RUnlock() {}
+
+func (s *Storage) WithTx(ctx context.Context, fn func(tx storage.Repos) error) error {
+	return s.WithTxOptions(ctx, storage.DefaultTxOptions, fn)
+}
+
+// WithTxOptions runs fn against the data itself and undoes its changes when
+// fn fails or panics, so a transaction costs as much as the changes it makes.
+// Transactions hold the write lock throughout, so they are always
+// serializable and never have to be retried; opts is ignored.
+//
+// The lock is not reentrant: fn must only use the repositories in tx. Calling
+// s itself from fn, or starting another transaction, blocks forever.
+func (s *Storage) WithTxOptions(_ context.Context, _ storage.TxOptions, fn func(tx storage.Repos) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &Storage{
+		mu:            noLock{},
+		users:         s.users,
+		usernames:     s.usernames,
+		listings:      s.listings,
+		tokens:        s.tokens,
+		lastUserID:    s.lastUserID,
+		lastListingID: s.lastListingID,
+		lastTokenID:   s.lastTokenID,
+		undo:          &undoLog{},
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.undo.rollback()
+		}
+	}()
+
+	if err := fn(storage.Repos{Users: tx, Listings: tx, RefreshTokens: tx}); err != nil {
+		return err
+	}
+
+	committed = true
+	s.lastUserID, s.lastListingID, s.lastTokenID = tx.lastUserID, tx.lastListingID, tx.lastTokenID
+	return nil
+}
+
+// undoLog records how to revert each change a transaction makes. A nil log,
+// the one outside transactions, records nothing.
+type undoLog struct {
+	steps []func()
+}
+
+func (u *undoLog) add(step func()) {
+	if u != nil {
+		u.steps = append(u.steps, step)
+	}
+}
+
+// rollback reverts the changes, newest first.
+func (u *undoLog) rollback() {
+	for i := len(u.steps) - 1; i >= 0; i-- {
+		u.steps[i]()
+	}
+	u.steps = nil
+}
@@ -0,0 +1,142 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+	"github.com/justcgh9/vk-internship-application/internal/storage/postgres"
+	"github.com/justcgh9/vk-internship-application/internal/storage/postgres/pgtest"
+	"github.com/justcgh9/vk-internship-application/internal/storage/storagetest"
+)
+
+func TestStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Backend {
+		return postgres.NewStorage(pgtest.Pool(t))
+	})
+}
+
+func TestStorage_RevocationConformance(t *testing.T) {
+	storagetest.RunRevocation(t, func(t *testing.T) storagetest.RevocationBackend {
+		return postgres.NewStorage(pgtest.Pool(t))
+	})
+}
+
+func TestStorage_DeleteUserCascades(t *testing.T) {
+	pool := pgtest.Pool(t)
+	store := postgres.NewStorage(pool)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+	l, err := store.CreateListing(ctx, &models.Listing{
+		Title: "Road bike", Description: "Barely used road bike", Price: 100, UserID: user.ID,
+	})
+	require.NoError(t, err)
+	_, err = store.CreateRefreshToken(ctx, &models.RefreshToken{
+		TokenHash: "hash", UserID: user.ID, FamilyID: "8f14e45f-ceea-467a-9575-0a3a1b1f1d2e", ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, user.ID)
+	require.NoError(t, err)
+
+	_, err = store.GetListingByID(ctx, l.ID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = store.GetRefreshToken(ctx, "hash")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+// Rows with equal sort keys must not be skipped or repeated between keyset
+// pages, which only holds if the id breaks the tie on both sides.
+func TestStorage_KeysetWithEqualTimestamps(t *testing.T) {
+	pool := pgtest.Pool(t)
+	store := postgres.NewStorage(pool)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err := store.CreateListing(ctx, &models.Listing{
+			Title: "Same time", Description: "Created at the same time", Price: 1, UserID: user.ID,
+		})
+		require.NoError(t, err)
+	}
+	_, err = pool.Exec(ctx, `UPDATE listings SET created_at = '2025-01-01 12:00:00'`)
+	require.NoError(t, err)
+
+	filter := storage.ListFilter{Limit: 2}
+	var walked []int64
+	for {
+		page, err := store.ListListings(ctx, filter)
+		require.NoError(t, err)
+		for _, l := range page {
+			walked = append(walked, l.ID)
+		}
+		if len(page) < filter.Limit {
+			break
+		}
+		filter.After = storage.CursorAfter(filter, page[len(page)-1])
+	}
+	assert.Equal(t, []int64{5, 4, 3, 2, 1}, walked)
+}
+
+// Expiry is an instant: the zone the app happens to pass the time in must not
+// move it.
+func TestStorage_RefreshTokenExpiryIgnoresTimeZone(t *testing.T) {
+	store := postgres.NewStorage(pgtest.Pool(t))
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		rotates   bool
+	}{
+		{"active, west of UTC", time.Now().Add(15 * time.Minute).In(time.FixedZone("UTC-5", -5*3600)), true},
+		{"expired, east of UTC", time.Now().Add(-time.Minute).In(time.FixedZone("UTC+5", 5*3600)), false},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := fmt.Sprintf("old-%d", i)
+			_, err := store.CreateRefreshToken(ctx, &models.RefreshToken{
+				TokenHash: old, UserID: user.ID, FamilyID: "8f14e45f-ceea-467a-9575-0a3a1b1f1d2e", ExpiresAt: tt.expiresAt,
+			})
+			require.NoError(t, err)
+
+			_, err = store.RotateRefreshToken(ctx, old, &models.RefreshToken{
+				TokenHash: fmt.Sprintf("next-%d", i), ExpiresAt: time.Now().Add(time.Hour),
+			})
+			if tt.rotates {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, storage.ErrNotFound)
+			}
+		})
+	}
+}
+
+// A revocation must outlive the purge of expired entries for as long as the
+// token is valid, whatever zone its expiry was passed in.
+func TestStorage_RevocationExpiryIgnoresTimeZone(t *testing.T) {
+	store := postgres.NewStorage(pgtest.Pool(t))
+	ctx := context.Background()
+
+	west := time.FixedZone("UTC-5", -5*3600)
+	require.NoError(t, store.RevokeToken(ctx, "jti", time.Now().Add(15*time.Minute).In(west)))
+	// Revoking another token purges the expired entries.
+	require.NoError(t, store.RevokeToken(ctx, "other", time.Now().Add(15*time.Minute)))
+
+	revoked, err := store.IsTokenRevoked(ctx, "jti")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
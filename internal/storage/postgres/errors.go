@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+)
+
+// SQLSTATE codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	stringDataRightTruncation = "22001"
+	numericValueOutOfRange    = "22003"
+	notNullViolation          = "23502"
+	foreignKeyViolation       = "23503"
+	uniqueViolation           = "23505"
+	checkViolation            = "23514"
+)
+
+// mapError translates pgx errors into the storage domain errors. The original
+// error stays in the chain for logs.
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ErrNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolation:
+			return fmt.Errorf("%w: %s", storage.ErrConflict, pgErr.ConstraintName)
+		case foreignKeyViolation:
+			// The row refers to an entity that does not exist (anymore).
+			return fmt.Errorf("%w: %s", storage.ErrNotFound, pgErr.ConstraintName)
+		case checkViolation, notNullViolation:
+			return fmt.Errorf("%w: %s", storage.ErrInvalid, pgErr.ConstraintName)
+		case stringDataRightTruncation, numericValueOutOfRange:
+			return fmt.Errorf("%w: %s", storage.ErrInvalid, pgErr.Message)
+		}
+	}
+	return err
+}
@@ -0,0 +1,12 @@
+package postgres_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/justcgh9/vk-internship-application/internal/storage/postgres/pgtest"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+package pgtest
+
+import (
+	"os"
+	"os/exec"
+)
+
+// owner is the account the server runs as. Outside Unix the server always
+// runs as the current user.
+type owner struct{}
+
+func serverUser() (*owner, error) { return nil, nil }
+
+func (o *owner) chown(string) error { return nil }
+
+func (o *owner) apply(*exec.Cmd) {}
+
+// ownedByMe cannot tell the owner of a file outside Unix; the permission
+// bits are all there is to check.
+func ownedByMe(os.FileInfo) bool { return true }
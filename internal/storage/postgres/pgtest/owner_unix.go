@@ -0,0 +1,68 @@
+//go:build unix
+
+package pgtest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// owner is the account the server runs as; nil means the current one.
+type owner struct {
+	uid, gid uint32
+}
+
+// serverUser picks the account for the server. initdb refuses to run as
+// root, so root hands the cluster over to PGTEST_USER, nobody by default.
+func serverUser() (*owner, error) {
+	if os.Geteuid() != 0 {
+		return nil, nil
+	}
+
+	name := os.Getenv(envUser)
+	if name == "" {
+		name = "nobody"
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("running as root and cannot find user %s to run postgres as (set %s): %w", name, envUser, err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	if uid == 0 {
+		return nil, fmt.Errorf("%s=%s is root; postgres needs an unprivileged user", envUser, name)
+	}
+	return &owner{uid: uint32(uid), gid: uint32(gid)}, nil
+}
+
+func (o *owner) chown(path string) error {
+	if o == nil {
+		return nil
+	}
+	return os.Chown(path, int(o.uid), int(o.gid))
+}
+
+func (o *owner) apply(cmd *exec.Cmd) {
+	if o == nil {
+		return
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: o.uid, Gid: o.gid},
+	}
+}
+
+// ownedByMe reports whether fi belongs to the current user.
+func ownedByMe(fi os.FileInfo) bool {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	return ok && int(st.Uid) == os.Geteuid()
+}
@@ -0,0 +1,251 @@
+// Package pgtest gives tests a real, migrated Postgres database.
+//
+// By default a throwaway server is started the first time a test asks for
+// it and stopped when the test binary exits. Nothing is downloaded: the
+// binaries come from EMBEDDED_POSTGRES_BINARIES (a directory with bin/pg_ctl
+// inside, such as a vendored copy), from an embedded-postgres-binaries
+// archive in EMBEDDED_POSTGRES_CACHE (~/.embedded-postgres-go by default), or
+// from a PostgreSQL installed on the machine. When the tests run as root, the
+// server runs as PGTEST_USER, nobody by default, since initdb refuses root.
+// TEST_DATABASE_URL skips the local server and uses an existing one instead.
+//
+// Every test binary works in a database of its own, created on the server
+// when it starts and dropped when it exits, so the packages that go test
+// runs in parallel do not empty each other's tables. With TEST_DATABASE_URL
+// the user must be allowed to create databases; the database named in the
+// URL is only used to connect and is left alone.
+//
+// When no database can be had, the tests that need one are skipped, unless
+// PGTEST_REQUIRED or CI is set, in which case they fail.
+package pgtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/justcgh9/vk-internship-application/internal/migrator"
+)
+
+const (
+	envDatabaseURL = "TEST_DATABASE_URL"
+	envBinaries    = "EMBEDDED_POSTGRES_BINARIES"
+	envCache       = "EMBEDDED_POSTGRES_CACHE"
+	envUser        = "PGTEST_USER"
+	envRequired    = "PGTEST_REQUIRED"
+)
+
+var (
+	once   sync.Once
+	pool   *pgxpool.Pool
+	stop   func()
+	setup  error
+	tables []string
+
+	// server is the connection string of the server's default database,
+	// used to create and drop the test databases.
+	server string
+	// drop removes the database of the test binary.
+	drop func()
+)
+
+// Main runs the tests of a package and stops the database afterwards. Call
+// it from TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+func Main(m *testing.M) int {
+	code := m.Run()
+	if pool != nil {
+		pool.Close()
+	}
+	if drop != nil {
+		drop()
+	}
+	if stop != nil {
+		stop()
+	}
+	return code
+}
+
+// Pool returns a pool connected to the test database with every table
+// emptied and every sequence reset. When there is no database to run
+// against, it skips the test, or fails it if Required.
+func Pool(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+
+	once.Do(func() { setup = start() })
+	if setup != nil {
+		if Required() {
+			t.Fatalf("pgtest: postgres is required (%s or CI is set) but not available: %v", envRequired, setup)
+		}
+		t.Skipf("postgres is not available: %v", setup)
+	}
+
+	_, err := pool.Exec(context.Background(), "TRUNCATE "+strings.Join(tables, ", ")+" RESTART IDENTITY CASCADE")
+	if err != nil {
+		t.Fatalf("pgtest: truncate tables: %v", err)
+	}
+	return pool
+}
+
+// Database creates an empty database that only the calling test uses and
+// returns its connection string. The database is dropped when the test ends.
+// It is for tests that change the schema itself; everything else should use
+// Pool.
+func Database(t testing.TB) string {
+	t.Helper()
+
+	once.Do(func() { setup = start() })
+	if setup != nil {
+		if Required() {
+			t.Fatalf("pgtest: postgres is required (%s or CI is set) but not available: %v", envRequired, setup)
+		}
+		t.Skipf("postgres is not available: %v", setup)
+	}
+
+	uri, dropDB, err := createDatabase()
+	if err != nil {
+		t.Fatalf("pgtest: %v", err)
+	}
+	t.Cleanup(dropDB)
+	return uri
+}
+
+func start() error {
+	server = os.Getenv(envDatabaseURL)
+	if server == "" {
+		var err error
+		if server, err = startEmbedded(); err != nil {
+			return err
+		}
+	}
+
+	uri, dropDB, err := createDatabase()
+	if err != nil {
+		return err
+	}
+	drop = dropDB
+
+	if err := migrateUp(uri); err != nil {
+		return err
+	}
+
+	if pool, err = pgxpool.New(context.Background(), uri); err != nil {
+		return err
+	}
+
+	rows, err := pool.Query(context.Background(), `
+		SELECT quote_ident(tablename)
+		FROM pg_tables
+		WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return err
+		}
+		tables = append(tables, table)
+	}
+	return rows.Err()
+}
+
+// Required reports whether the environment expects a database, so that its
+// absence is an error rather than a reason to skip.
+func Required() bool {
+	for _, env := range []string{envRequired, "CI"} {
+		if v := os.Getenv(env); v != "" {
+			if on, err := strconv.ParseBool(v); err != nil || on {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// createDatabase creates a database with a random name on the server and
+// returns its connection string along with a function that drops it.
+func createDatabase() (string, func(), error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", nil, err
+	}
+	name := fmt.Sprintf("pgtest_%d_%s", os.Getpid(), hex.EncodeToString(suffix))
+
+	if err := execServer(fmt.Sprintf("CREATE DATABASE %s", name)); err != nil {
+		return "", nil, fmt.Errorf("create database: %w", err)
+	}
+	uri, err := withDatabase(server, name)
+	if err != nil {
+		return "", nil, err
+	}
+	return uri, func() { _ = execServer(fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", name)) }, nil
+}
+
+// execServer runs sql on the server's default database, outside of the test
+// databases, which cannot be created or dropped while connected to them.
+func execServer(sql string) error {
+	conn, err := pgx.Connect(context.Background(), server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(context.Background(), sql)
+	return err
+}
+
+// withDatabase points the connection string uri, a URL or keyword/value
+// pairs, at the database name.
+func withDatabase(uri, name string) (string, error) {
+	if !strings.HasPrefix(uri, "postgres://") && !strings.HasPrefix(uri, "postgresql://") {
+		// The last dbname wins.
+		return uri + " dbname=" + name, nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("parse %s: %w", envDatabaseURL, err)
+	}
+	u.Path = "/" + name
+	return u.String(), nil
+}
+
+func migrateUp(uri string) error {
+	src, err := migrator.Source("")
+	if err != nil {
+		return err
+	}
+	m, err := migrator.New(src, uri)
+	if err != nil {
+		return fmt.Errorf("init migrations: %w", err)
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil {
+		return fmt.Errorf("apply migrations: %w", err)
+	}
+	return nil
+}
+
+func freePort() (uint32, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return uint32(l.Addr().(*net.TCPAddr).Port), nil
+}
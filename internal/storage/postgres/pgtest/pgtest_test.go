@@ -0,0 +1,32 @@
+package pgtest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/justcgh9/vk-internship-application/internal/storage/postgres/pgtest"
+)
+
+func TestRequired(t *testing.T) {
+	tests := []struct {
+		required, ci string
+		want         bool
+	}{
+		{"", "", false},
+		{"true", "", true},
+		{"1", "", true},
+		{"false", "", false},
+		{"", "true", true},
+		{"", "woodpecker", true},
+		{"", "false", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.required+"/"+tt.ci, func(t *testing.T) {
+			t.Setenv("PGTEST_REQUIRED", tt.required)
+			t.Setenv("CI", tt.ci)
+			assert.Equal(t, tt.want, pgtest.Required())
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package pgtest
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/xi2/xz"
+)
+
+// startEmbedded initializes a throwaway cluster in a temporary directory and
+// starts a server on a free port. The binaries are only looked up on this
+// machine; nothing is downloaded.
+func startEmbedded() (uri string, err error) {
+	dir, removeBinaries, err := binaries()
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			removeBinaries()
+		}
+	}()
+
+	owner, err := serverUser()
+	if err != nil {
+		return "", err
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return "", err
+	}
+
+	runtimePath, err := os.MkdirTemp("", "pgtest-")
+	if err != nil {
+		return "", err
+	}
+	if err := owner.chown(runtimePath); err != nil {
+		_ = os.RemoveAll(runtimePath)
+		return "", err
+	}
+
+	data := filepath.Join(runtimePath, "data")
+	logFile := filepath.Join(runtimePath, "postgres.log")
+	run := func(name string, args ...string) error {
+		var out bytes.Buffer
+		cmd := exec.Command(filepath.Join(dir, "bin", name), args...)
+		cmd.Dir = runtimePath
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		owner.apply(cmd)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w\n%s", name, err, out.String())
+		}
+		return nil
+	}
+
+	err = run("initdb", "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync")
+	if err == nil {
+		err = run("pg_ctl", "start", "-w", "-t", "60", "-D", data, "-l", logFile, "-o", fmt.Sprintf(
+			"-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off -c synchronous_commit=off -c full_page_writes=off",
+			port, runtimePath,
+		))
+	}
+	if err != nil {
+		logs, _ := os.ReadFile(logFile)
+		_ = os.RemoveAll(runtimePath)
+		return "", fmt.Errorf("start postgres from %s: %w\n%s", dir, err, logs)
+	}
+
+	stop = func() {
+		_ = run("pg_ctl", "stop", "-w", "-D", data, "-m", "immediate")
+		_ = os.RemoveAll(runtimePath)
+		removeBinaries()
+	}
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port), nil
+}
+
+// binaries returns the directory that holds bin/initdb and bin/pg_ctl and a
+// function that removes it once the server is gone, if it is temporary. In
+// order it tries EMBEDDED_POSTGRES_BINARIES, an embedded-postgres archive in
+// the cache, and a PostgreSQL installed on the machine.
+func binaries() (dir string, cleanup func(), err error) {
+	if dir := os.Getenv(envBinaries); dir != "" {
+		if !hasBinaries(dir) {
+			return "", nil, fmt.Errorf("%s=%s has no bin/pg_ctl", envBinaries, dir)
+		}
+		return dir, func() {}, nil
+	}
+
+	archive, err := cachedArchive()
+	if err != nil {
+		return "", nil, err
+	}
+	if archive != "" {
+		return extract(archive)
+	}
+
+	if dir := installed(); dir != "" {
+		return dir, func() {}, nil
+	}
+	return "", nil, fmt.Errorf("no PostgreSQL binaries found: set %s, put an embedded-postgres-binaries archive into %s or install PostgreSQL",
+		envBinaries, cacheDir())
+}
+
+func hasBinaries(dir string) bool {
+	for _, name := range []string{"initdb", "pg_ctl"} {
+		if _, err := os.Stat(filepath.Join(dir, "bin", name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func cacheDir() string {
+	if dir := os.Getenv(envCache); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".embedded-postgres-go"
+	}
+	return filepath.Join(home, ".embedded-postgres-go")
+}
+
+// cachedArchive finds the newest archive for this platform that
+// embedded-postgres left in its cache, named like
+// embedded-postgres-binaries-linux-amd64-16.9.0.txz.
+func cachedArchive() (string, error) {
+	arch := runtime.GOARCH
+	if runtime.GOOS == "linux" && arch == "arm64" {
+		arch = "arm64v8"
+	}
+	if _, err := os.Stat("/etc/alpine-release"); err == nil {
+		arch += "-alpine"
+	}
+	prefix := fmt.Sprintf("embedded-postgres-binaries-%s-%s-", runtime.GOOS, arch)
+	version := regexp.MustCompile(`^\d+(\.\d+)*\.txz$`)
+
+	entries, err := os.ReadDir(cacheDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var archives []string
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, prefix) && version.MatchString(strings.TrimPrefix(name, prefix)) {
+			archives = append(archives, name)
+		}
+	}
+	if len(archives) == 0 {
+		return "", nil
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		return versionLess(strings.TrimPrefix(archives[i], prefix), strings.TrimPrefix(archives[j], prefix))
+	})
+	return filepath.Join(cacheDir(), archives[len(archives)-1]), nil
+}
+
+func versionLess(a, b string) bool {
+	pa := strings.Split(strings.TrimSuffix(a, ".txz"), ".")
+	pb := strings.Split(strings.TrimSuffix(b, ".txz"), ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if len(pa[i]) != len(pb[i]) {
+			return len(pa[i]) < len(pb[i])
+		}
+		if pa[i] != pb[i] {
+			return pa[i] < pb[i]
+		}
+	}
+	return len(pa) < len(pb)
+}
+
+// installed looks for the binaries of a system-wide PostgreSQL.
+func installed() string {
+	if pgConfig, err := exec.LookPath("pg_config"); err == nil {
+		if out, err := exec.Command(pgConfig, "--bindir").Output(); err == nil {
+			if dir := filepath.Dir(strings.TrimSpace(string(out))); hasBinaries(dir) {
+				return dir
+			}
+		}
+	}
+	if pgCtl, err := exec.LookPath("pg_ctl"); err == nil {
+		if dir := filepath.Dir(filepath.Dir(pgCtl)); hasBinaries(dir) {
+			return dir
+		}
+	}
+
+	for _, pattern := range []string{
+		"/usr/lib/postgresql/*/bin/pg_ctl", // Debian, Ubuntu
+		"/usr/pgsql-*/bin/pg_ctl",          // RHEL and PGDG packages
+		"/opt/homebrew/opt/postgresql*/bin/pg_ctl",
+		"/usr/local/opt/postgresql*/bin/pg_ctl",
+	} {
+		matches, _ := filepath.Glob(pattern)
+		sort.Slice(matches, func(i, j int) bool { return versionLess(matches[i], matches[j]) })
+		for i := len(matches) - 1; i >= 0; i-- {
+			if dir := filepath.Dir(filepath.Dir(matches[i])); hasBinaries(dir) {
+				return dir
+			}
+		}
+	}
+	return ""
+}
+
+// extract unpacks archive once into the temporary directory, where the
+// unprivileged server user can read it, and reuses it on later runs. Test
+// binaries of several packages may race here; the loser drops its copy.
+//
+// Anyone can create the shared directory, so it is only trusted when it
+// belongs to the current user and nobody else can write to it. Otherwise the
+// archive goes into a private directory that cleanup removes.
+func extract(archive string) (dir string, cleanup func(), err error) {
+	target := filepath.Join(os.TempDir(), "pgtest-"+strings.TrimSuffix(filepath.Base(archive), ".txz"))
+	if trusted(target) && hasBinaries(target) {
+		return target, func() {}, nil
+	}
+
+	tmp, err := os.MkdirTemp(os.TempDir(), "pgtest-extract-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { _ = os.RemoveAll(tmp) }
+
+	if err := untarXZ(archive, tmp); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("extract %s: %w", archive, err)
+	}
+	if err := os.Chmod(tmp, 0o755); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	if err := os.Rename(tmp, target); err == nil {
+		return target, func() {}, nil
+	}
+	if trusted(target) && hasBinaries(target) {
+		cleanup()
+		return target, func() {}, nil
+	}
+	return tmp, cleanup, nil
+}
+
+// trusted reports whether dir is a real directory of the current user that
+// no one else can write to.
+func trusted(dir string) bool {
+	fi, err := os.Lstat(dir)
+	if err != nil || !fi.IsDir() {
+		return false
+	}
+	return fi.Mode().Perm()&0o022 == 0 && ownedByMe(fi)
+}
+
+func untarXZ(archive, dest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	xzr, err := xz.NewReader(f, 0)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(xzr)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dest, hdr.Name)
+		if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
+			return fmt.Errorf("entry %q escapes the archive", hdr.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, path); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			mode := os.FileMode(hdr.Mode).Perm()&^0o022 | 0o444
+			out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
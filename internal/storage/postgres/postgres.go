@@ -3,16 +3,25 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"html"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/justcgh9/vk-internship-application/internal/models"
 	"github.com/justcgh9/vk-internship-application/internal/storage"
+	"github.com/justcgh9/vk-internship-application/internal/storage/postgres/sqlbuilder"
 )
 
+// DB is satisfied by pgxpool.Pool as well as by pgx.Tx, so a Storage can be
+// bound to an open transaction.
 type DB interface {
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
 type Storage struct {
@@ -29,36 +38,61 @@ func (s *Storage) CreateUser(ctx context.Context, username, passwordHash string)
 	row := s.db.QueryRow(ctx, `
 		INSERT INTO users (username, password_hash)
 		VALUES ($1, $2)
-		RETURNING id, username, created_at
+		RETURNING id, username, role, created_at
 	`, username, passwordHash)
 
 	u := &models.User{}
-	err := row.Scan(&u.ID, &u.Username, &u.CreatedAt)
-	return u, err
+	err := row.Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return u, nil
 }
 
 func (s *Storage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	row := s.db.QueryRow(ctx, `
-		SELECT id, username, password_hash, created_at
+		SELECT id, username, password_hash, role, created_at
 		FROM users
 		WHERE username = $1
 	`, username)
 
 	u := &models.User{}
-	err := row.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.CreatedAt)
-	return u, err
+	err := row.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.CreatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return u, nil
 }
 
 func (s *Storage) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
 	row := s.db.QueryRow(ctx, `
-		SELECT id, username, password_hash, created_at
+		SELECT id, username, password_hash, role, created_at
 		FROM users
 		WHERE id = $1
 	`, id)
 
 	u := &models.User{}
-	err := row.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.CreatedAt)
-	return u, err
+	err := row.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.CreatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return u, nil
+}
+
+func (s *Storage) UpdateUserRole(ctx context.Context, id int64, role models.Role) (*models.User, error) {
+	row := s.db.QueryRow(ctx, `
+		UPDATE users
+		SET role = $2
+		WHERE id = $1
+		RETURNING id, username, role, created_at
+	`, id, role)
+
+	u := &models.User{}
+	err := row.Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return u, nil
 }
 
 // --- ListingRepository ---
@@ -71,68 +105,374 @@ func (s *Storage) CreateListing(ctx context.Context, l *models.Listing) (*models
 	`, l.Title, l.Description, l.ImageURL, l.Price, l.UserID)
 
 	err := row.Scan(&l.ID, &l.CreatedAt)
-	return l, err
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return l, nil
+}
+
+func (s *Storage) GetListingByID(ctx context.Context, id int64) (*models.Listing, error) {
+	row := s.db.QueryRow(ctx, `
+		SELECT id, title, description, image_url, price, user_id, created_at
+		FROM listings
+		WHERE id = $1
+	`, id)
+
+	l := &models.Listing{}
+	err := row.Scan(&l.ID, &l.Title, &l.Description, &l.ImageURL, &l.Price, &l.UserID, &l.CreatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return l, nil
+}
+
+func (s *Storage) UpdateListing(ctx context.Context, id int64, upd storage.ListingUpdate) (*models.Listing, error) {
+	row := s.db.QueryRow(ctx, `
+		UPDATE listings
+		SET title = COALESCE($2, title),
+			description = COALESCE($3, description),
+			image_url = COALESCE($4, image_url),
+			price = COALESCE($5, price)
+		WHERE id = $1
+		RETURNING id, title, description, image_url, price, user_id, created_at
+	`, id, upd.Title, upd.Description, upd.ImageURL, upd.Price)
+
+	l := &models.Listing{}
+	err := row.Scan(&l.ID, &l.Title, &l.Description, &l.ImageURL, &l.Price, &l.UserID, &l.CreatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return l, nil
+}
+
+func (s *Storage) DeleteListing(ctx context.Context, id int64) error {
+	row := s.db.QueryRow(ctx, `
+		DELETE FROM listings
+		WHERE id = $1
+		RETURNING id
+	`, id)
+
+	var deletedID int64
+	err := row.Scan(&deletedID)
+	return mapError(err)
 }
 
 func (s *Storage) ListListings(ctx context.Context, filter storage.ListFilter) ([]*models.ListingWithAuthor, error) {
-	query := `
-		SELECT 
-			l.id, l.title, l.description, l.image_url, l.price, u.username, l.user_id, l.created_at
-		FROM listings l
-		JOIN users u ON l.user_id = u.id
-		WHERE 1=1
-	`
-
-	args := []any{}
-	argID := 1
-
-	// Add price filtering
+	listings, _, err := s.listListings(ctx, filter, false)
+	return listings, err
+}
+
+// ListListingsWithTotal is ListListings plus the number of listings matching
+// the filter regardless of pagination. The count is taken with COUNT(*) OVER()
+// in the same query, so it costs a full scan of the matching rows.
+func (s *Storage) ListListingsWithTotal(ctx context.Context, filter storage.ListFilter) ([]*models.ListingWithAuthor, int, error) {
+	listings, total, err := s.listListings(ctx, filter, true)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// A page past the end has no rows to carry the window count.
+	if len(listings) == 0 && (filter.Offset > 0 || filter.After != nil) {
+		q := sqlbuilder.Select("COUNT(*)").
+			From("listings l").
+			Join("users u ON l.user_id = u.id")
+		filterListings(q, filter)
+
+		sql, args := q.SQL()
+		if err := s.db.QueryRow(ctx, sql, args...).Scan(&total); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return listings, total, nil
+}
+
+// searchQuery matches words in either language; %[1]s is the placeholder of
+// the raw user input.
+const searchQuery = `(websearch_to_tsquery('russian', %[1]s) || websearch_to_tsquery('english', %[1]s))`
+
+// Highlighted terms are wrapped in control characters by Postgres and turned
+// into <mark> tags only after the rest of the snippet has been escaped.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+// filterListings adds the filter predicates that do not depend on the page
+// position. It returns the tsquery expression when the filter is a search.
+func filterListings(q *sqlbuilder.SelectBuilder, filter storage.ListFilter) (tsquery string) {
+	if filter.Query != "" {
+		tsquery = fmt.Sprintf(searchQuery, q.Arg(filter.Query))
+		q.Where("l.search_vector @@ " + tsquery)
+	}
+
 	if filter.PriceMin != nil {
-		query += fmt.Sprintf(" AND l.price >= $%d", argID)
-		args = append(args, *filter.PriceMin)
-		argID++
+		q.Where("l.price >= ?", *filter.PriceMin)
 	}
 	if filter.PriceMax != nil {
-		query += fmt.Sprintf(" AND l.price <= $%d", argID)
-		args = append(args, *filter.PriceMax)
-		argID++
+		q.Where("l.price <= ?", *filter.PriceMax)
 	}
 
-	// Sorting
-	sortBy := "l.created_at"
-	if filter.SortBy == "price" {
-		sortBy = "l.price"
+	if filter.AuthorID != nil {
+		q.Where("l.user_id = ?", *filter.AuthorID)
+	}
+	if filter.AuthorLogin != "" {
+		q.Where("u.username = ?", filter.AuthorLogin)
 	}
-	sortOrder := "DESC"
-	if filter.SortOrder == "asc" {
-		sortOrder = "ASC"
+	if filter.OnlyMine && filter.ViewerID != nil {
+		q.Where("l.user_id = ?", *filter.ViewerID)
+	}
+
+	// created_at is a timestamp without time zone; its default stores UTC
+	// whatever the server's TimeZone (migration 0006).
+	if filter.CreatedAfter != nil {
+		q.Where("l.created_at >= ?", filter.CreatedAfter.UTC())
+	}
+	if filter.CreatedBefore != nil {
+		q.Where("l.created_at < ?", filter.CreatedBefore.UTC())
+	}
+
+	if filter.HasImage != nil {
+		if *filter.HasImage {
+			q.Where("l.image_url IS NOT NULL AND l.image_url <> ''")
+		} else {
+			q.Where("(l.image_url IS NULL OR l.image_url = '')")
+		}
 	}
-	query += fmt.Sprintf(" ORDER BY %s %s", sortBy, sortOrder)
 
-	// Pagination
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argID, argID+1)
-	args = append(args, filter.Limit, filter.Offset)
+	return tsquery
+}
+
+var listingColumns = []string{
+	"l.id", "l.title", "l.description", "l.image_url", "l.price", "u.username", "l.user_id", "l.created_at",
+}
+
+func (s *Storage) listListings(ctx context.Context, filter storage.ListFilter, withTotal bool) ([]*models.ListingWithAuthor, int, error) {
+	q := sqlbuilder.Select(listingColumns...).
+		From("listings l").
+		Join("users u ON l.user_id = u.id")
+
+	tsquery := filterListings(q, filter)
+	search := tsquery != ""
+
+	rank := "l.rank"
+	if search {
+		q.Columns(fmt.Sprintf("ts_rank(l.search_vector, %s) AS rank", tsquery))
+		if !withTotal {
+			rank = fmt.Sprintf("ts_rank(l.search_vector, %s)", tsquery)
+		}
+	}
 
-	rows, err := s.db.Query(ctx, query, args...)
+	// The window count has to see every matching row, so the page position
+	// is applied outside of it.
+	if withTotal {
+		q.Columns("COUNT(*) OVER() AS total")
+
+		outer := []string{"l.id", "l.title", "l.description", "l.image_url", "l.price", "l.username", "l.user_id", "l.created_at"}
+		if search {
+			outer = append(outer, "l.rank")
+		}
+		q = q.Wrap("l", outer...)
+	}
+
+	// Snippets are only built for the rows of the page itself.
+	if search {
+		q.Columns(fmt.Sprintf(`ts_headline('russian', l.title || ' — ' || l.description, %s, `+
+			`'StartSel=%s, StopSel=%s, MaxFragments=2, MinWords=5, MaxWords=20') AS highlight`,
+			tsquery, highlightStart, highlightStop))
+	}
+	if withTotal {
+		q.Columns("l.total")
+	}
+
+	// Sorting, with the id as a tiebreaker so that keyset pages are stable
+	sortBy, sortOrder := filter.Sort()
+	column, cast := "l.created_at", "timestamp"
+	switch sortBy {
+	case storage.SortByPrice:
+		column, cast = "l.price", "numeric"
+	case storage.SortByRelevance:
+		column, cast = rank, "real"
+	}
+	direction, op := "DESC", "<"
+	if sortOrder == storage.SortAsc {
+		direction, op = "ASC", ">"
+	}
+
+	// Keyset pagination
+	if filter.After != nil {
+		q.Where(fmt.Sprintf("(%s, l.id) %s (?::%s, ?)", column, op, cast), filter.After.Value, filter.After.ID)
+	}
+
+	q.OrderBy(column+" "+direction, "l.id "+direction).Limit(filter.Limit)
+	if filter.After == nil {
+		q.Offset(filter.Offset)
+	}
+
+	sql, args := q.SQL()
+	rows, err := s.db.Query(ctx, sql, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
 	var listings []*models.ListingWithAuthor
+	var total int
 	for rows.Next() {
 		var l models.ListingWithAuthor
 		var authorID int64
-		if err := rows.Scan(
+		dest := []any{
 			&l.ID, &l.Title, &l.Description, &l.ImageURL, &l.Price,
 			&l.AuthorLogin, &authorID, &l.CreatedAt,
-		); err != nil {
-			return nil, err
+		}
+		if search {
+			dest = append(dest, &l.Relevance, &l.Highlight)
+		}
+		if withTotal {
+			dest = append(dest, &total)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, 0, err
 		}
 		if filter.ViewerID != nil && *filter.ViewerID == authorID {
 			l.IsOwned = true
 		}
+		l.Highlight = markHighlight(l.Highlight)
 		listings = append(listings, &l)
 	}
-	return listings, nil
+	return listings, total, rows.Err()
+}
+
+func markHighlight(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		highlightStart, "<mark>",
+		highlightStop, "</mark>",
+	).Replace(html.EscapeString(raw))
+}
+
+// --- RefreshTokenRepository ---
+
+func (s *Storage) CreateRefreshToken(ctx context.Context, t *models.RefreshToken) (*models.RefreshToken, error) {
+	row := s.db.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (token_hash, user_id, family_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, t.TokenHash, t.UserID, t.FamilyID, t.ExpiresAt)
+
+	err := row.Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return t, nil
+}
+
+func (s *Storage) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	row := s.db.QueryRow(ctx, `
+		SELECT id, token_hash, user_id, family_id::text, expires_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`, tokenHash)
+
+	t := &models.RefreshToken{}
+	err := row.Scan(&t.ID, &t.TokenHash, &t.UserID, &t.FamilyID, &t.ExpiresAt, &t.RevokedAt, &t.CreatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return t, nil
+}
+
+func (s *Storage) RotateRefreshToken(ctx context.Context, oldHash string, next *models.RefreshToken) (*models.RefreshToken, error) {
+	row := s.db.QueryRow(ctx, `
+		WITH revoked AS (
+			UPDATE refresh_tokens
+			SET revoked_at = NOW()
+			WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+			RETURNING user_id, family_id
+		)
+		INSERT INTO refresh_tokens (token_hash, user_id, family_id, expires_at)
+		SELECT $2, user_id, family_id, $3 FROM revoked
+		RETURNING id, user_id, family_id::text, created_at
+	`, oldHash, next.TokenHash, next.ExpiresAt)
+
+	err := row.Scan(&next.ID, &next.UserID, &next.FamilyID, &next.CreatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return next, nil
+}
+
+func (s *Storage) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`, familyID)
+	return err
+}
+
+func (s *Storage) RevokeUserRefreshTokens(ctx context.Context, userID int64) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	return err
+}
+
+// --- RevocationStore ---
+
+func (s *Storage) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := s.db.Exec(ctx, `
+		WITH purged AS (
+			DELETE FROM revoked_tokens WHERE expires_at < NOW()
+		)
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`, jti, expiresAt)
+	return err
+}
+
+func (s *Storage) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	row := s.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+	`, jti)
+
+	var revoked bool
+	err := row.Scan(&revoked)
+	return revoked, err
+}
+
+func (s *Storage) GetTokenVersion(ctx context.Context, userID int64) (int, error) {
+	row := s.db.QueryRow(ctx, `
+		SELECT token_version
+		FROM users
+		WHERE id = $1
+	`, userID)
+
+	var version int
+	err := row.Scan(&version)
+	if err != nil {
+		return 0, mapError(err)
+	}
+	return version, nil
+}
+
+func (s *Storage) IncrementTokenVersion(ctx context.Context, userID int64) (int, error) {
+	row := s.db.QueryRow(ctx, `
+		UPDATE users
+		SET token_version = token_version + 1
+		WHERE id = $1
+		RETURNING token_version
+	`, userID)
+
+	var version int
+	err := row.Scan(&version)
+	if err != nil {
+		return 0, mapError(err)
+	}
+	return version, nil
 }
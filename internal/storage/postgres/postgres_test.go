@@ -9,6 +9,7 @@ import (
 	"unsafe"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
 
@@ -25,8 +26,8 @@ func TestCreateUser_Success(t *testing.T) {
 	store := &postgres.Storage{}
 	setFieldValue(store, "db", mockConn)
 
-	rows := pgxmock.NewRows([]string{"id", "username", "created_at"}).
-		AddRow(int64(1), "alice", time.Now())
+	rows := pgxmock.NewRows([]string{"id", "username", "role", "created_at"}).
+		AddRow(int64(1), "alice", models.RoleUser, time.Now())
 
 	mockConn.ExpectQuery(`INSERT INTO users`).
 		WithArgs("alice", "hashedpassword").
@@ -59,6 +60,25 @@ func TestCreateUser_QueryError(t *testing.T) {
 	assert.NoError(t, mockConn.ExpectationsWereMet())
 }
 
+func TestCreateUser_DuplicateUsername(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	mockConn.ExpectQuery(`INSERT INTO users`).
+		WithArgs("alice", "hashedpassword").
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "users_username_key"})
+
+	ctx := context.Background()
+	_, err = store.CreateUser(ctx, "alice", "hashedpassword")
+	assert.ErrorIs(t, err, storage.ErrConflict)
+	assert.Contains(t, err.Error(), "users_username_key")
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
 func TestGetUserByUsername_Success(t *testing.T) {
 	mockConn, err := pgxmock.NewPool()
 	assert.NoError(t, err)
@@ -67,10 +87,10 @@ func TestGetUserByUsername_Success(t *testing.T) {
 	store := &postgres.Storage{}
 	setFieldValue(store, "db", mockConn)
 
-	rows := pgxmock.NewRows([]string{"id", "username", "password_hash", "created_at"}).
-		AddRow(int64(1), "bob", "hashed", time.Now())
+	rows := pgxmock.NewRows([]string{"id", "username", "password_hash", "role", "created_at"}).
+		AddRow(int64(1), "bob", "hashed", models.RoleUser, time.Now())
 
-	mockConn.ExpectQuery(`SELECT id, username, password_hash, created_at FROM users WHERE username = \$1`).
+	mockConn.ExpectQuery(`SELECT id, username, password_hash, role, created_at FROM users WHERE username = \$1`).
 		WithArgs("bob").
 		WillReturnRows(rows)
 
@@ -90,13 +110,13 @@ func TestGetUserByUsername_NotFound(t *testing.T) {
 	store := &postgres.Storage{}
 	setFieldValue(store, "db", mockConn)
 
-	mockConn.ExpectQuery(`SELECT id, username, password_hash, created_at FROM users WHERE username = \$1`).
+	mockConn.ExpectQuery(`SELECT id, username, password_hash, role, created_at FROM users WHERE username = \$1`).
 		WithArgs("nonexistent").
 		WillReturnError(pgx.ErrNoRows)
 
 	ctx := context.Background()
 	_, err = store.GetUserByUsername(ctx, "nonexistent")
-	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
 	assert.NoError(t, mockConn.ExpectationsWereMet())
 }
 
@@ -124,10 +144,10 @@ func TestGetUserByID_Success(t *testing.T) {
 	setFieldValue(store, "db", mockConn)
 
 	expectedTime := time.Now()
-	rows := pgxmock.NewRows([]string{"id", "username", "password_hash", "created_at"}).
-		AddRow(int64(2), "alice", "hash123", expectedTime)
+	rows := pgxmock.NewRows([]string{"id", "username", "password_hash", "role", "created_at"}).
+		AddRow(int64(2), "alice", "hash123", models.RoleModerator, expectedTime)
 
-	mockConn.ExpectQuery(`SELECT id, username, password_hash, created_at FROM users WHERE id = \$1`).
+	mockConn.ExpectQuery(`SELECT id, username, password_hash, role, created_at FROM users WHERE id = \$1`).
 		WithArgs(int64(2)).
 		WillReturnRows(rows)
 
@@ -137,6 +157,7 @@ func TestGetUserByID_Success(t *testing.T) {
 	assert.Equal(t, int64(2), u.ID)
 	assert.Equal(t, "alice", u.Username)
 	assert.Equal(t, "hash123", u.PasswordHash)
+	assert.Equal(t, models.RoleModerator, u.Role)
 	assert.WithinDuration(t, expectedTime, u.CreatedAt, time.Second)
 	assert.NoError(t, mockConn.ExpectationsWereMet())
 }
@@ -149,13 +170,13 @@ func TestGetUserByID_NotFound(t *testing.T) {
 	store := &postgres.Storage{}
 	setFieldValue(store, "db", mockConn)
 
-	mockConn.ExpectQuery(`SELECT id, username, password_hash, created_at FROM users WHERE id = \$1`).
+	mockConn.ExpectQuery(`SELECT id, username, password_hash, role, created_at FROM users WHERE id = \$1`).
 		WithArgs(int64(99)).
 		WillReturnError(pgx.ErrNoRows)
 
 	ctx := context.Background()
 	_, err = store.GetUserByID(ctx, 99)
-	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
 	assert.NoError(t, mockConn.ExpectationsWereMet())
 }
 
@@ -307,3 +328,472 @@ func TestListListings_ScanError(t *testing.T) {
 	assert.Contains(t, err.Error(), "destination kind 'int64' not supported for value kind 'string' of column 'id'")
 	assert.NoError(t, mockConn.ExpectationsWereMet())
 }
+
+func TestListListings_Keyset(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	filter := storage.ListFilter{
+		Limit:  10,
+		Offset: 20,
+		After:  &storage.Cursor{SortBy: "created_at", SortOrder: "desc", Value: "2025-01-01T10:00:00Z", ID: 42},
+	}
+
+	rows := pgxmock.NewRows([]string{
+		"id", "title", "description", "image_url", "price", "username", "user_id", "created_at",
+	}).AddRow(int64(41), "Item", "desc", "img", 1000.0, "bob", int64(1), time.Now())
+
+	mockConn.ExpectQuery(`WHERE \(l.created_at, l.id\) < \(\$1::timestamp, \$2\) ORDER BY l.created_at DESC, l.id DESC LIMIT \$3$`).
+		WithArgs("2025-01-01T10:00:00Z", int64(42), filter.Limit).
+		WillReturnRows(rows)
+
+	results, err := store.ListListings(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestListListingsWithTotal_Success(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	filter := storage.ListFilter{Limit: 10, WithTotal: true}
+
+	rows := pgxmock.NewRows([]string{
+		"id", "title", "description", "image_url", "price", "username", "user_id", "created_at", "total",
+	}).
+		AddRow(int64(1), "Item 1", "desc", "img", 1000.0, "bob", int64(1), time.Now(), 37).
+		AddRow(int64(2), "Item 2", "desc", "img", 2000.0, "bob", int64(1), time.Now(), 37)
+
+	mockConn.ExpectQuery(`COUNT\(\*\) OVER\(\) AS total`).
+		WithArgs(filter.Limit, filter.Offset).
+		WillReturnRows(rows)
+
+	results, total, err := store.ListListingsWithTotal(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, 37, total)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestListListingsWithTotal_PastLastPage(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	min := 100.0
+	filter := storage.ListFilter{Limit: 10, Offset: 50, PriceMin: &min, WithTotal: true}
+
+	mockConn.ExpectQuery(`COUNT\(\*\) OVER\(\) AS total`).
+		WithArgs(min, filter.Limit, filter.Offset).
+		WillReturnRows(pgxmock.NewRows([]string{
+			"id", "title", "description", "image_url", "price", "username", "user_id", "created_at", "total",
+		}))
+	mockConn.ExpectQuery(`SELECT COUNT\(\*\) FROM listings l JOIN users u ON l.user_id = u.id WHERE l.price >= \$1`).
+		WithArgs(min).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(12))
+
+	results, total, err := store.ListListingsWithTotal(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Equal(t, 12, total)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestListListings_Search(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	max := 5000.0
+	filter := storage.ListFilter{Limit: 10, Query: "велосипед", PriceMax: &max}
+
+	rows := pgxmock.NewRows([]string{
+		"id", "title", "description", "image_url", "price", "username", "user_id", "created_at", "rank", "highlight",
+	}).AddRow(int64(1), "Велосипед", "desc", "img", 3000.0, "bob", int64(1), time.Now(),
+		float32(0.6), "\x02Велосипед\x03 — <b>почти</b> новый")
+
+	mockConn.ExpectQuery(`WHERE l.search_vector @@ \(websearch_to_tsquery\('russian', \$1\) \|\| websearch_to_tsquery\('english', \$1\)\) AND l.price <= \$2 ORDER BY ts_rank\(.+\) DESC, l.id DESC LIMIT \$3 OFFSET \$4`).
+		WithArgs("велосипед", max, filter.Limit, filter.Offset).
+		WillReturnRows(rows)
+
+	results, err := store.ListListings(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, float32(0.6), results[0].Relevance)
+	assert.Equal(t, "<mark>Велосипед</mark> — &lt;b&gt;почти&lt;/b&gt; новый", results[0].Highlight)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestListListingsWithTotal_SearchKeyset(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	filter := storage.ListFilter{
+		Limit:     10,
+		Query:     "bike",
+		WithTotal: true,
+		After:     &storage.Cursor{SortBy: "relevance", SortOrder: "desc", Value: "0.5", ID: 3},
+	}
+
+	rows := pgxmock.NewRows([]string{
+		"id", "title", "description", "image_url", "price", "username", "user_id", "created_at", "rank", "highlight", "total",
+	}).AddRow(int64(2), "Bike", "desc", "img", 100.0, "bob", int64(1), time.Now(), float32(0.4), "\x02Bike\x03", 8)
+
+	mockConn.ExpectQuery(`\) l WHERE \(l.rank, l.id\) < \(\$2::real, \$3\) ORDER BY l.rank DESC, l.id DESC LIMIT \$4$`).
+		WithArgs("bike", "0.5", int64(3), filter.Limit).
+		WillReturnRows(rows)
+
+	results, total, err := store.ListListingsWithTotal(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "<mark>Bike</mark>", results[0].Highlight)
+	assert.Equal(t, 8, total)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestListListings_ExtendedFilters(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	authorID := int64(5)
+	viewerID := int64(7)
+	after := time.Date(2025, 1, 1, 3, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	before := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	hasImage := false
+	filter := storage.ListFilter{
+		Limit:         10,
+		AuthorID:      &authorID,
+		AuthorLogin:   "bob",
+		CreatedAfter:  &after,
+		CreatedBefore: &before,
+		HasImage:      &hasImage,
+		ViewerID:      &viewerID,
+		OnlyMine:      true,
+	}
+
+	mockConn.ExpectQuery(`WHERE l.user_id = \$1 AND u.username = \$2 AND l.user_id = \$3`+
+		` AND l.created_at >= \$4 AND l.created_at < \$5 AND \(l.image_url IS NULL OR l.image_url = ''\)`+
+		` ORDER BY l.created_at DESC, l.id DESC LIMIT \$6 OFFSET \$7`).
+		WithArgs(authorID, "bob", viewerID, after.UTC(), before, filter.Limit, filter.Offset).
+		WillReturnRows(pgxmock.NewRows([]string{
+			"id", "title", "description", "image_url", "price", "username", "user_id", "created_at",
+		}))
+
+	results, err := store.ListListings(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestCreateListing_UnknownAuthor(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	listing := &models.Listing{Title: "Cool Shirt", Description: "Black shirt with logo", Price: 2500, UserID: 404}
+
+	mockConn.ExpectQuery(`INSERT INTO listings`).
+		WithArgs(listing.Title, listing.Description, listing.ImageURL, listing.Price, listing.UserID).
+		WillReturnError(&pgconn.PgError{Code: "23503", ConstraintName: "listings_user_id_fkey"})
+
+	ctx := context.Background()
+	_, err = store.CreateListing(ctx, listing)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestGetListingByID_Success(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	rows := pgxmock.NewRows([]string{"id", "title", "description", "image_url", "price", "user_id", "created_at"}).
+		AddRow(int64(4), "Lamp", "Desk lamp", "img", 300.0, int64(2), time.Now())
+
+	mockConn.ExpectQuery(`SELECT id, title, description, image_url, price, user_id, created_at FROM listings WHERE id = \$1`).
+		WithArgs(int64(4)).
+		WillReturnRows(rows)
+
+	l, err := store.GetListingByID(context.Background(), 4)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), l.ID)
+	assert.Equal(t, int64(2), l.UserID)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestGetListingByID_NotFound(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	mockConn.ExpectQuery(`SELECT .* FROM listings WHERE id = \$1`).
+		WithArgs(int64(4)).
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err = store.GetListingByID(context.Background(), 4)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestUpdateListing_Success(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	title := "Brighter lamp"
+	upd := storage.ListingUpdate{Title: &title}
+
+	rows := pgxmock.NewRows([]string{"id", "title", "description", "image_url", "price", "user_id", "created_at"}).
+		AddRow(int64(4), title, "Desk lamp", "img", 300.0, int64(2), time.Now())
+
+	mockConn.ExpectQuery(`UPDATE listings SET`).
+		WithArgs(int64(4), upd.Title, upd.Description, upd.ImageURL, upd.Price).
+		WillReturnRows(rows)
+
+	l, err := store.UpdateListing(context.Background(), 4, upd)
+	assert.NoError(t, err)
+	assert.Equal(t, title, l.Title)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestUpdateListing_NotFound(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	price := 10.0
+	upd := storage.ListingUpdate{Price: &price}
+
+	mockConn.ExpectQuery(`UPDATE listings SET`).
+		WithArgs(int64(4), upd.Title, upd.Description, upd.ImageURL, upd.Price).
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err = store.UpdateListing(context.Background(), 4, upd)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestUpdateListing_CheckViolation(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	title := "ab"
+	upd := storage.ListingUpdate{Title: &title}
+
+	mockConn.ExpectQuery(`UPDATE listings SET`).
+		WithArgs(int64(4), upd.Title, upd.Description, upd.ImageURL, upd.Price).
+		WillReturnError(&pgconn.PgError{Code: "23514", ConstraintName: "listings_title_check"})
+
+	_, err = store.UpdateListing(context.Background(), 4, upd)
+	assert.ErrorIs(t, err, storage.ErrInvalid)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestDeleteListing_Success(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	mockConn.ExpectQuery(`DELETE FROM listings WHERE id = \$1 RETURNING id`).
+		WithArgs(int64(4)).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(4)))
+
+	err = store.DeleteListing(context.Background(), 4)
+	assert.NoError(t, err)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestDeleteListing_NotFound(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	mockConn.ExpectQuery(`DELETE FROM listings`).
+		WithArgs(int64(4)).
+		WillReturnError(pgx.ErrNoRows)
+
+	err = store.DeleteListing(context.Background(), 4)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestRotateRefreshToken_Success(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	next := &models.RefreshToken{TokenHash: "new-hash", ExpiresAt: time.Now().Add(time.Hour)}
+	rows := pgxmock.NewRows([]string{"id", "user_id", "family_id", "created_at"}).
+		AddRow(int64(2), int64(7), "family", time.Now())
+
+	mockConn.ExpectQuery(`WITH revoked AS \(\s*UPDATE refresh_tokens`).
+		WithArgs("old-hash", next.TokenHash, next.ExpiresAt).
+		WillReturnRows(rows)
+
+	rotated, err := store.RotateRefreshToken(context.Background(), "old-hash", next)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), rotated.UserID)
+	assert.Equal(t, "family", rotated.FamilyID)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestRotateRefreshToken_NotActive(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	next := &models.RefreshToken{TokenHash: "new-hash", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mockConn.ExpectQuery(`WITH revoked AS`).
+		WithArgs("old-hash", next.TokenHash, next.ExpiresAt).
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err = store.RotateRefreshToken(context.Background(), "old-hash", next)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestRevokeRefreshTokenFamily(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	mockConn.ExpectExec(`UPDATE refresh_tokens SET revoked_at = NOW\(\) WHERE family_id = \$1`).
+		WithArgs("family").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+
+	err = store.RevokeRefreshTokenFamily(context.Background(), "family")
+	assert.NoError(t, err)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestRevokeToken(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	expiresAt := time.Now().Add(time.Minute)
+	mockConn.ExpectExec(`INSERT INTO revoked_tokens`).
+		WithArgs("jti", expiresAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	err = store.RevokeToken(context.Background(), "jti", expiresAt)
+	assert.NoError(t, err)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	mockConn.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM revoked_tokens WHERE jti = \$1\)`).
+		WithArgs("jti").
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+
+	revoked, err := store.IsTokenRevoked(context.Background(), "jti")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestIncrementTokenVersion_NotFound(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	mockConn.ExpectQuery(`UPDATE users SET token_version = token_version \+ 1`).
+		WithArgs(int64(9)).
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err = store.IncrementTokenVersion(context.Background(), 9)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestUpdateUserRole_Success(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := &postgres.Storage{}
+	setFieldValue(store, "db", mockConn)
+
+	rows := pgxmock.NewRows([]string{"id", "username", "role", "created_at"}).
+		AddRow(int64(3), "carol", models.RoleAdmin, time.Now())
+
+	mockConn.ExpectQuery(`UPDATE users SET role = \$2 WHERE id = \$1`).
+		WithArgs(int64(3), models.RoleAdmin).
+		WillReturnRows(rows)
+
+	u, err := store.UpdateUserRole(context.Background(), 3, models.RoleAdmin)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, u.Role)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
@@ -0,0 +1,130 @@
+// Package sqlbuilder assembles parameterized Postgres SELECT statements one
+// clause at a time, numbering $n placeholders in the order arguments are
+// added.
+package sqlbuilder
+
+import (
+	"fmt"
+	"strings"
+)
+
+type SelectBuilder struct {
+	columns []string
+	from    string
+	joins   []string
+	where   []string
+	orderBy []string
+	limit   string
+	offset  string
+	args    []any
+}
+
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns}
+}
+
+// Columns appends to the select list.
+func (s *SelectBuilder) Columns(columns ...string) *SelectBuilder {
+	s.columns = append(s.columns, columns...)
+	return s
+}
+
+func (s *SelectBuilder) From(table string) *SelectBuilder {
+	s.from = table
+	return s
+}
+
+// Join adds an inner join, e.g. Join("users u ON l.user_id = u.id").
+func (s *SelectBuilder) Join(join string) *SelectBuilder {
+	s.joins = append(s.joins, join)
+	return s
+}
+
+// Arg binds v and returns its placeholder, for expressions that refer to the
+// same argument more than once.
+func (s *SelectBuilder) Arg(v any) string {
+	s.args = append(s.args, v)
+	return fmt.Sprintf("$%d", len(s.args))
+}
+
+// Where adds a condition; conditions are joined with AND as is, so
+// disjunctions must be parenthesized. Every ? in cond is bound to the next
+// argument in args.
+func (s *SelectBuilder) Where(cond string, args ...any) *SelectBuilder {
+	if n := strings.Count(cond, "?"); n != len(args) {
+		panic(fmt.Sprintf("sqlbuilder: %q has %d placeholders, got %d arguments", cond, n, len(args)))
+	}
+
+	var b strings.Builder
+	for _, arg := range args {
+		i := strings.IndexByte(cond, '?')
+		b.WriteString(cond[:i])
+		b.WriteString(s.Arg(arg))
+		cond = cond[i+1:]
+	}
+	b.WriteString(cond)
+
+	s.where = append(s.where, b.String())
+	return s
+}
+
+func (s *SelectBuilder) OrderBy(exprs ...string) *SelectBuilder {
+	s.orderBy = append(s.orderBy, exprs...)
+	return s
+}
+
+func (s *SelectBuilder) Limit(n int) *SelectBuilder {
+	s.limit = s.Arg(n)
+	return s
+}
+
+func (s *SelectBuilder) Offset(n int) *SelectBuilder {
+	s.offset = s.Arg(n)
+	return s
+}
+
+// Wrap turns the statement into a subquery named alias and starts a new
+// statement selecting columns from it. Placeholders keep their numbering, so
+// the outer statement can keep adding arguments.
+func (s *SelectBuilder) Wrap(alias string, columns ...string) *SelectBuilder {
+	sql, args := s.SQL()
+	return &SelectBuilder{
+		columns: columns,
+		from:    fmt.Sprintf("(%s) %s", sql, alias),
+		args:    args,
+	}
+}
+
+// SQL renders the statement and returns it with its arguments.
+func (s *SelectBuilder) SQL() (string, []any) {
+	var b strings.Builder
+
+	b.WriteString("SELECT ")
+	b.WriteString(strings.Join(s.columns, ", "))
+	if s.from != "" {
+		b.WriteString(" FROM ")
+		b.WriteString(s.from)
+	}
+	for _, join := range s.joins {
+		b.WriteString(" JOIN ")
+		b.WriteString(join)
+	}
+	if len(s.where) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(s.where, " AND "))
+	}
+	if len(s.orderBy) > 0 {
+		b.WriteString(" ORDER BY ")
+		b.WriteString(strings.Join(s.orderBy, ", "))
+	}
+	if s.limit != "" {
+		b.WriteString(" LIMIT ")
+		b.WriteString(s.limit)
+	}
+	if s.offset != "" {
+		b.WriteString(" OFFSET ")
+		b.WriteString(s.offset)
+	}
+
+	return b.String(), append([]any(nil), s.args...)
+}
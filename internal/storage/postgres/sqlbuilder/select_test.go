@@ -0,0 +1,66 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/justcgh9/vk-internship-application/internal/storage/postgres/sqlbuilder"
+)
+
+func TestSelect_Plain(t *testing.T) {
+	sql, args := sqlbuilder.Select("id", "title").From("listings").SQL()
+
+	assert.Equal(t, "SELECT id, title FROM listings", sql)
+	assert.Empty(t, args)
+}
+
+func TestSelect_AllClauses(t *testing.T) {
+	sql, args := sqlbuilder.Select("l.id", "u.username").
+		From("listings l").
+		Join("users u ON l.user_id = u.id").
+		Where("l.price >= ?", 10.0).
+		Where("(l.image_url IS NULL OR l.image_url = '')").
+		Where("(l.created_at, l.id) < (?::timestamp, ?)", "2025-01-01T00:00:00Z", int64(4)).
+		OrderBy("l.created_at DESC", "l.id DESC").
+		Limit(20).
+		Offset(40).
+		SQL()
+
+	assert.Equal(t, "SELECT l.id, u.username FROM listings l JOIN users u ON l.user_id = u.id"+
+		" WHERE l.price >= $1 AND (l.image_url IS NULL OR l.image_url = '')"+
+		" AND (l.created_at, l.id) < ($2::timestamp, $3)"+
+		" ORDER BY l.created_at DESC, l.id DESC LIMIT $4 OFFSET $5", sql)
+	assert.Equal(t, []any{10.0, "2025-01-01T00:00:00Z", int64(4), 20, 40}, args)
+}
+
+func TestSelect_ReusedArg(t *testing.T) {
+	q := sqlbuilder.Select("id").From("listings")
+	p := q.Arg("bike")
+	q.Columns("ts_rank(v, to_tsquery(" + p + "))").Where("v @@ to_tsquery(" + p + ")")
+
+	sql, args := q.SQL()
+	assert.Equal(t, "SELECT id, ts_rank(v, to_tsquery($1)) FROM listings WHERE v @@ to_tsquery($1)", sql)
+	assert.Equal(t, []any{"bike"}, args)
+}
+
+func TestSelect_Wrap(t *testing.T) {
+	inner := sqlbuilder.Select("id", "COUNT(*) OVER() AS total").
+		From("listings").
+		Where("price <= ?", 5)
+
+	sql, args := inner.Wrap("l", "l.id", "l.total").
+		Where("l.id > ?", 3).
+		Limit(10).
+		SQL()
+
+	assert.Equal(t, "SELECT l.id, l.total FROM (SELECT id, COUNT(*) OVER() AS total FROM listings WHERE price <= $1) l"+
+		" WHERE l.id > $2 LIMIT $3", sql)
+	assert.Equal(t, []any{5, 3, 10}, args)
+}
+
+func TestSelect_WherePlaceholderMismatch(t *testing.T) {
+	assert.Panics(t, func() {
+		sqlbuilder.Select("id").Where("a = ? AND b = ?", 1)
+	})
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+// maxStatementLength bounds the SQL put on spans and in logs; the listing
+// queries built by sqlbuilder get long.
+const maxStatementLength = 2048
+
+// QueryTracer makes every query a child span of the request that ran it and
+// logs the ones slower than a threshold. Plug it into the pool through
+// pgxpool.Config.ConnConfig.Tracer.
+type QueryTracer struct {
+	slowThreshold time.Duration
+}
+
+// NewQueryTracer logs queries that take slowThreshold or longer; zero turns
+// slow query logging off.
+func NewQueryTracer(slowThreshold time.Duration) *QueryTracer {
+	return &QueryTracer{slowThreshold: slowThreshold}
+}
+
+type queryKey struct{}
+
+type queryStart struct {
+	sql   string
+	start time.Time
+}
+
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	sql := sanitizeSQL(data.SQL)
+	op := operation(sql)
+
+	ctx, _ = otel.Tracer("vk-intern-app").Start(ctx, "db."+strings.ToLower(op),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", op),
+			attribute.String("db.statement", sql),
+		),
+	)
+	return context.WithValue(ctx, queryKey{}, queryStart{sql: sql, start: time.Now()})
+}
+
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	rows := data.CommandTag.RowsAffected()
+	span.SetAttributes(attribute.Int64("db.rows_affected", rows))
+
+	if data.Err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(data.Err, &pgErr) {
+			span.SetAttributes(attribute.String("db.postgresql.sqlstate", pgErr.Code))
+		}
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, "query failed")
+	}
+
+	q, ok := ctx.Value(queryKey{}).(queryStart)
+	if !ok || t.slowThreshold <= 0 {
+		return
+	}
+	if elapsed := time.Since(q.start); elapsed >= t.slowThreshold {
+		logger.FromContext(ctx).
+			With("component", "postgres").
+			Warn("slow query",
+				slog.String("sql", q.sql),
+				slog.Duration("duration", elapsed),
+				slog.Int64("rows", rows),
+			)
+	}
+}
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`(^|[^$\w])\d+(?:\.\d+)?\b`) // but not $1
+)
+
+// sanitizeSQL squeezes whitespace and masks literals. Values normally come
+// as $n arguments, which are never recorded, but a literal could still
+// carry data and must not end up in traces or logs.
+func sanitizeSQL(sql string) string {
+	sql = stringLiteral.ReplaceAllString(sql, "?")
+	sql = numericLiteral.ReplaceAllString(sql, "${1}?")
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > maxStatementLength {
+		// Cut at a rune boundary so that the result stays valid UTF-8.
+		cut := maxStatementLength
+		for cut > 0 && !utf8.RuneStart(sql[cut]) {
+			cut--
+		}
+		sql = sql[:cut] + "…"
+	}
+	return sql
+}
+
+// operation is the leading keyword of a statement, such as SELECT.
+func operation(sql string) string {
+	op, _, _ := strings.Cut(sql, " ")
+	if op == "" {
+		return "QUERY"
+	}
+	return strings.ToUpper(op)
+}
@@ -0,0 +1,142 @@
+package postgres_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/justcgh9/vk-internship-application/internal/storage/postgres"
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return recorder
+}
+
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	prev := logger.Log
+	logger.Log = slog.New(slog.NewJSONHandler(&buf, nil))
+	t.Cleanup(func() { logger.Log = prev })
+	return &buf
+}
+
+func attrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestQueryTracer_Span(t *testing.T) {
+	recorder := recordSpans(t)
+	tracer := postgres.NewQueryTracer(0)
+
+	parentCtx, parent := otel.Tracer("test").Start(context.Background(), "handler")
+	ctx := tracer.TraceQueryStart(parentCtx, nil, pgx.TraceQueryStartData{
+		SQL: `
+			SELECT id FROM listings
+			WHERE title = 'secret title' AND price > 100.5 AND user_id = $1
+		`,
+		Args: []any{int64(42)},
+	})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 3")})
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	query := spans[0]
+
+	assert.Equal(t, "db.select", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+	a := attrs(query)
+	assert.Equal(t, "SELECT id FROM listings WHERE title = ? AND price > ? AND user_id = $1", a["db.statement"].AsString())
+	assert.Equal(t, "postgresql", a["db.system"].AsString())
+	assert.Equal(t, int64(3), a["db.rows_affected"].AsInt64())
+	assert.Equal(t, codes.Unset, query.Status().Code)
+}
+
+func TestQueryTracer_TruncatesAtRuneBoundary(t *testing.T) {
+	recorder := recordSpans(t)
+	tracer := postgres.NewQueryTracer(0)
+
+	// "—" takes three bytes and straddles the 2048-byte limit.
+	prefix := "SELECT " + strings.Repeat("x", 2047-len("SELECT "))
+	sql := prefix + "—" + strings.Repeat("y", 100)
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: sql})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	statement := attrs(spans[0])["db.statement"].AsString()
+	assert.True(t, utf8.ValidString(statement))
+	assert.Equal(t, prefix+"…", statement)
+}
+
+func TestQueryTracer_Error(t *testing.T) {
+	recorder := recordSpans(t)
+	tracer := postgres.NewQueryTracer(0)
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "INSERT INTO users (username) VALUES ($1)"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: &pgconn.PgError{Code: "23505", Message: "duplicate key"}})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "23505", attrs(spans[0])["db.postgresql.sqlstate"].AsString())
+	require.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, "exception", spans[0].Events()[0].Name)
+}
+
+func TestQueryTracer_SlowQueryLog(t *testing.T) {
+	recordSpans(t)
+	logs := captureLogs(t)
+	tracer := postgres.NewQueryTracer(time.Millisecond)
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT pg_sleep($1)"})
+	time.Sleep(2 * time.Millisecond)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1")})
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &line))
+	assert.Equal(t, "slow query", line["msg"])
+	assert.Equal(t, "SELECT pg_sleep($1)", line["sql"])
+	assert.NotEmpty(t, line["trace_id"])
+	assert.Contains(t, line, "duration")
+}
+
+func TestQueryTracer_FastQueryNotLogged(t *testing.T) {
+	recordSpans(t)
+	logs := captureLogs(t)
+	tracer := postgres.NewQueryTracer(time.Hour)
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	assert.Empty(t, logs.String())
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+)
+
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// txBeginner is implemented by pgxpool.Pool and pgx.Conn. A pgx.Tx can only
+// Begin, which opens a savepoint.
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+}
+
+func (s *Storage) WithTx(ctx context.Context, fn func(tx storage.Repos) error) error {
+	return s.WithTxOptions(ctx, storage.DefaultTxOptions, fn)
+}
+
+// WithTxOptions runs fn in a new transaction. When the storage itself is
+// bound to a transaction, fn runs in a savepoint of it instead: the
+// isolation level is inherited and retrying is left to the outer unit of
+// work.
+func (s *Storage) WithTxOptions(ctx context.Context, opts storage.TxOptions, fn func(tx storage.Repos) error) error {
+	beginner, ok := s.db.(txBeginner)
+	if !ok {
+		return s.runTx(ctx, s.db.Begin, fn)
+	}
+
+	begin := func(ctx context.Context) (pgx.Tx, error) {
+		return beginner.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.TxIsoLevel(opts.Isolation)})
+	}
+	for attempt := 0; ; attempt++ {
+		err := s.runTx(ctx, begin, fn)
+		if attempt >= opts.MaxRetries || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(retryDelay(attempt)):
+		}
+	}
+}
+
+func (s *Storage) runTx(ctx context.Context, begin func(context.Context) (pgx.Tx, error), fn func(tx storage.Repos) error) error {
+	tx, err := begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	txStore := &Storage{db: tx}
+	if err := fn(storage.Repos{Users: txStore, Listings: txStore, RefreshTokens: txStore}); err != nil {
+		return err
+	}
+
+	committed = true
+	return tx.Commit(ctx)
+}
+
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+}
+
+// retryDelay backs off linearly with jitter, so that transactions that
+// collided once do not collide again right away.
+func retryDelay(attempt int) time.Duration {
+	base := time.Duration(attempt+1) * 10 * time.Millisecond
+	return base + rand.N(base)
+}
@@ -0,0 +1,142 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+	"github.com/justcgh9/vk-internship-application/internal/storage/postgres"
+)
+
+func createUserInTx(ctx context.Context) func(tx storage.Repos) error {
+	return func(tx storage.Repos) error {
+		_, err := tx.Users.CreateUser(ctx, "alice", "hash")
+		return err
+	}
+}
+
+func userRows() *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"id", "username", "role", "created_at"}).
+		AddRow(int64(1), "alice", models.RoleUser, time.Now())
+}
+
+func TestWithTx_Commit(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := postgres.NewStorage(mockConn)
+
+	mockConn.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mockConn.ExpectQuery(`INSERT INTO users`).WithArgs("alice", "hash").WillReturnRows(userRows())
+	mockConn.ExpectCommit()
+
+	err = store.WithTx(context.Background(), createUserInTx(context.Background()))
+	assert.NoError(t, err)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestWithTx_RollbackOnError(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := postgres.NewStorage(mockConn)
+	errAbort := errors.New("abort")
+
+	mockConn.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mockConn.ExpectQuery(`INSERT INTO users`).WithArgs("alice", "hash").WillReturnRows(userRows())
+	mockConn.ExpectRollback()
+
+	err = store.WithTx(context.Background(), func(tx storage.Repos) error {
+		if err := createUserInTx(context.Background())(tx); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestWithTxOptions_RetriesSerializationFailure(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := postgres.NewStorage(mockConn)
+	opts := storage.TxOptions{Isolation: storage.Serializable, MaxRetries: 1}
+
+	mockConn.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.Serializable})
+	mockConn.ExpectQuery(`INSERT INTO users`).WithArgs("alice", "hash").WillReturnError(&pgconn.PgError{Code: "40001"})
+	mockConn.ExpectRollback()
+	mockConn.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.Serializable})
+	mockConn.ExpectQuery(`INSERT INTO users`).WithArgs("alice", "hash").WillReturnRows(userRows())
+	mockConn.ExpectCommit()
+
+	err = store.WithTxOptions(context.Background(), opts, createUserInTx(context.Background()))
+	assert.NoError(t, err)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestWithTxOptions_GivesUpAfterMaxRetries(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := postgres.NewStorage(mockConn)
+	opts := storage.TxOptions{Isolation: storage.RepeatableRead, MaxRetries: 0}
+
+	mockConn.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.RepeatableRead})
+	mockConn.ExpectQuery(`INSERT INTO users`).WithArgs("alice", "hash").WillReturnError(&pgconn.PgError{Code: "40001"})
+	mockConn.ExpectRollback()
+
+	err = store.WithTxOptions(context.Background(), opts, createUserInTx(context.Background()))
+	var pgErr *pgconn.PgError
+	assert.ErrorAs(t, err, &pgErr)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestWithTx_DoesNotRetryOtherErrors(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	store := postgres.NewStorage(mockConn)
+
+	mockConn.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mockConn.ExpectQuery(`INSERT INTO users`).WithArgs("alice", "hash").WillReturnError(&pgconn.PgError{Code: "23505"})
+	mockConn.ExpectRollback()
+
+	err = store.WithTx(context.Background(), createUserInTx(context.Background()))
+	assert.ErrorIs(t, err, storage.ErrConflict)
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
+
+func TestWithTx_NestedUsesSavepoint(t *testing.T) {
+	mockConn, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockConn.Close()
+
+	ctx := context.Background()
+	mockConn.ExpectBegin()
+	outer, err := mockConn.Begin(ctx)
+	assert.NoError(t, err)
+
+	store := postgres.NewStorage(outer)
+
+	mockConn.ExpectBegin()
+	mockConn.ExpectQuery(`INSERT INTO users`).WithArgs("alice", "hash").WillReturnError(&pgconn.PgError{Code: "40001"})
+	mockConn.ExpectRollback()
+
+	err = store.WithTxOptions(ctx, storage.TxOptions{MaxRetries: 3}, createUserInTx(ctx))
+	assert.Error(t, err, "retrying is left to the outer transaction")
+	assert.NoError(t, mockConn.ExpectationsWereMet())
+}
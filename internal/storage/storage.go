@@ -2,28 +2,148 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/justcgh9/vk-internship-application/internal/models"
 )
 
+// Repositories translate driver errors into these so that callers never
+// depend on a particular database.
+var (
+	ErrNotFound = errors.New("entity not found")
+	ErrConflict = errors.New("entity already exists")
+	// ErrInvalid means the entity breaks a constraint of the schema, such as
+	// a length or range check.
+	ErrInvalid = errors.New("entity violates a constraint")
+)
+
 type UserRepository interface {
 	CreateUser(ctx context.Context, username, passwordHash string) (*models.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
 	GetUserByID(ctx context.Context, id int64) (*models.User, error)
+	UpdateUserRole(ctx context.Context, id int64, role models.Role) (*models.User, error)
 }
 
 type ListingRepository interface {
 	CreateListing(ctx context.Context, l *models.Listing) (*models.Listing, error)
+	GetListingByID(ctx context.Context, id int64) (*models.Listing, error)
+	UpdateListing(ctx context.Context, id int64, upd ListingUpdate) (*models.Listing, error)
+	DeleteListing(ctx context.Context, id int64) error
 
 	ListListings(ctx context.Context, filter ListFilter) ([]*models.ListingWithAuthor, error)
+	// ListListingsWithTotal also returns how many listings match the filter
+	// across all pages.
+	ListListingsWithTotal(ctx context.Context, filter ListFilter) ([]*models.ListingWithAuthor, int, error)
+}
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, t *models.RefreshToken) (*models.RefreshToken, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// RotateRefreshToken atomically revokes the active token identified by
+	// oldHash and issues next in the same family. It returns ErrNotFound when
+	// the old token is missing, expired or already revoked.
+	RotateRefreshToken(ctx context.Context, oldHash string, next *models.RefreshToken) (*models.RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID int64) error
+}
+
+// RevocationStore tracks access tokens that were invalidated before they
+// expired, either one by one (by jti) or all at once per user (by version).
+type RevocationStore interface {
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	GetTokenVersion(ctx context.Context, userID int64) (int, error)
+	IncrementTokenVersion(ctx context.Context, userID int64) (int, error)
+}
+
+// Repos are the repositories of one unit of work; everything done through
+// them commits or rolls back together.
+type Repos struct {
+	Users         UserRepository
+	Listings      ListingRepository
+	RefreshTokens RefreshTokenRepository
+}
+
+type IsolationLevel string
+
+const (
+	ReadCommitted  IsolationLevel = "read committed"
+	RepeatableRead IsolationLevel = "repeatable read"
+	Serializable   IsolationLevel = "serializable"
+)
+
+// TxOptions tune a unit of work. A transaction that fails to serialize,
+// which only happens above ReadCommitted or on a deadlock, is run again from
+// scratch up to MaxRetries times.
+type TxOptions struct {
+	Isolation  IsolationLevel
+	MaxRetries int
+}
+
+var DefaultTxOptions = TxOptions{Isolation: ReadCommitted, MaxRetries: 3}
+
+// UnitOfWork runs fn in a transaction that is committed when fn returns nil
+// and rolled back otherwise. fn may run more than once, so it must not have
+// side effects outside of tx.
+type UnitOfWork interface {
+	WithTx(ctx context.Context, fn func(tx Repos) error) error
+	WithTxOptions(ctx context.Context, opts TxOptions, fn func(tx Repos) error) error
 }
 
 type ListFilter struct {
-	Limit     int
-	Offset    int
+	Limit  int
+	Offset int
+	// After switches to keyset pagination: Offset is ignored and the page
+	// starts right after the cursor position.
+	After *Cursor
+	// WithTotal asks for the total number of matching listings.
+	WithTotal bool
+	// Query is a full-text search over titles and descriptions.
+	Query     string
 	SortBy    string
 	SortOrder string
 	PriceMin  *float64
 	PriceMax  *float64
-	ViewerID  *int64
+
+	AuthorID      *int64
+	AuthorLogin   string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// HasImage keeps only listings with (true) or without (false) an image.
+	HasImage *bool
+
+	// ViewerID marks the viewer's own listings; with OnlyMine set it also
+	// restricts the result to them.
+	ViewerID *int64
+	OnlyMine bool
+}
+
+// Sort returns the normalized sort key and direction. Searches are ranked by
+// relevance unless asked otherwise; everything else falls back to the newest
+// listings first.
+func (f ListFilter) Sort() (sortBy, sortOrder string) {
+	switch {
+	case f.SortBy == SortByPrice:
+		sortBy = SortByPrice
+	case f.SortBy == SortByCreatedAt:
+		sortBy = SortByCreatedAt
+	case f.Query != "":
+		sortBy = SortByRelevance
+	default:
+		sortBy = SortByCreatedAt
+	}
+	sortOrder = SortDesc
+	if f.SortOrder == SortAsc {
+		sortOrder = SortAsc
+	}
+	return sortBy, sortOrder
+}
+
+// ListingUpdate describes a partial update: nil fields are left untouched.
+type ListingUpdate struct {
+	Title       *string
+	Description *string
+	ImageURL    *string
+	Price       *float64
 }
@@ -0,0 +1,605 @@
+// Package storagetest is a conformance suite for implementations of the
+// storage repositories. Every backend runs it from its own tests, so that
+// they stay interchangeable.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/internal/models"
+	"github.com/justcgh9/vk-internship-application/internal/storage"
+)
+
+// Backend is what the suite exercises.
+type Backend interface {
+	storage.UserRepository
+	storage.ListingRepository
+	storage.UnitOfWork
+}
+
+// Run runs the suite. newBackend is called once per test and must return an
+// empty backend.
+func Run(t *testing.T, newBackend func(t *testing.T) Backend) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, b Backend)
+	}{
+		{"CreateUser", testCreateUser},
+		{"CreateUserDuplicate", testCreateUserDuplicate},
+		{"GetUserNotFound", testGetUserNotFound},
+		{"UpdateUserRole", testUpdateUserRole},
+		{"UserConstraints", testUserConstraints},
+		{"CreateListing", testCreateListing},
+		{"CreateListingUnknownAuthor", testCreateListingUnknownAuthor},
+		{"UpdateListing", testUpdateListing},
+		{"DeleteListing", testDeleteListing},
+		{"ListingConstraints", testListingConstraints},
+		{"ListSorting", testListSorting},
+		{"ListKeysetPagination", testListKeysetPagination},
+		{"ListOffsetPagination", testListOffsetPagination},
+		{"ListFilters", testListFilters},
+		{"ListOwnership", testListOwnership},
+		{"ListSearch", testListSearch},
+		{"TxCommit", testTxCommit},
+		{"TxRollback", testTxRollback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newBackend(t))
+		})
+	}
+}
+
+func testCreateUser(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	created, err := b.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+	assert.NotZero(t, created.ID)
+	assert.Equal(t, "alice", created.Username)
+	assert.Equal(t, models.RoleUser, created.Role)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	byName, err := b.GetUserByUsername(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, byName.ID)
+	assert.Equal(t, "hash", byName.PasswordHash)
+
+	byID, err := b.GetUserByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", byID.Username)
+}
+
+func testCreateUserDuplicate(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	_, err := b.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+
+	_, err = b.CreateUser(ctx, "alice", "other")
+	assert.ErrorIs(t, err, storage.ErrConflict)
+}
+
+func testGetUserNotFound(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	_, err := b.GetUserByUsername(ctx, "ghost")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	_, err = b.GetUserByID(ctx, 404)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testUpdateUserRole(t *testing.T, b Backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice")
+
+	updated, err := b.UpdateUserRole(ctx, user.ID, models.RoleModerator)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleModerator, updated.Role)
+
+	stored, err := b.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleModerator, stored.Role)
+
+	_, err = b.UpdateUserRole(ctx, 404, models.RoleAdmin)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testUserConstraints(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	_, err := b.CreateUser(ctx, strings.Repeat("a", 33), "hash")
+	assert.ErrorIs(t, err, storage.ErrInvalid)
+
+	user := createUser(t, b, strings.Repeat("я", 32))
+	_, err = b.UpdateUserRole(ctx, user.ID, models.Role("owner"))
+	assert.ErrorIs(t, err, storage.ErrInvalid)
+}
+
+func testCreateListing(t *testing.T, b Backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice")
+
+	created, err := b.CreateListing(ctx, &models.Listing{
+		Title:       "Road bike",
+		Description: "Barely used road bike",
+		ImageURL:    "https://img.example.com/bike.png",
+		Price:       1499.5,
+		UserID:      user.ID,
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, created.ID)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	stored, err := b.GetListingByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Road bike", stored.Title)
+	assert.Equal(t, "Barely used road bike", stored.Description)
+	assert.Equal(t, "https://img.example.com/bike.png", stored.ImageURL)
+	assert.Equal(t, 1499.5, stored.Price)
+	assert.Equal(t, user.ID, stored.UserID)
+
+	_, err = b.GetListingByID(ctx, 404)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testCreateListingUnknownAuthor(t *testing.T, b Backend) {
+	_, err := b.CreateListing(context.Background(), &models.Listing{
+		Title:       "Orphan",
+		Description: "Listing without an author",
+		Price:       1,
+		UserID:      404,
+	})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testUpdateListing(t *testing.T, b Backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice")
+	l := createListing(t, b, user.ID, "Road bike", "Barely used road bike", 100)
+
+	title, price := "Gravel bike", 120.0
+	updated, err := b.UpdateListing(ctx, l.ID, storage.ListingUpdate{Title: &title, Price: &price})
+	require.NoError(t, err)
+	assert.Equal(t, "Gravel bike", updated.Title)
+	assert.Equal(t, "Barely used road bike", updated.Description)
+	assert.Equal(t, 120.0, updated.Price)
+
+	_, err = b.UpdateListing(ctx, 404, storage.ListingUpdate{Title: &title})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testDeleteListing(t *testing.T, b Backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice")
+	l := createListing(t, b, user.ID, "Road bike", "Barely used road bike", 100)
+
+	require.NoError(t, b.DeleteListing(ctx, l.ID))
+
+	_, err := b.GetListingByID(ctx, l.ID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	assert.ErrorIs(t, b.DeleteListing(ctx, l.ID), storage.ErrNotFound)
+}
+
+func testListingConstraints(t *testing.T, b Backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice")
+
+	valid := models.Listing{Title: "Road bike", Description: "Barely used road bike", Price: 100, UserID: user.ID}
+	tests := []struct {
+		name   string
+		modify func(l *models.Listing)
+	}{
+		{"short title", func(l *models.Listing) { l.Title = "ab" }},
+		{"long title", func(l *models.Listing) { l.Title = strings.Repeat("a", 101) }},
+		{"short description", func(l *models.Listing) { l.Description = "too short" }},
+		{"negative price", func(l *models.Listing) { l.Price = -1 }},
+		{"price out of range", func(l *models.Listing) { l.Price = 1e8 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := valid
+			tt.modify(&l)
+			_, err := b.CreateListing(ctx, &l)
+			assert.ErrorIs(t, err, storage.ErrInvalid)
+		})
+	}
+
+	// Multibyte titles are measured in characters.
+	l := valid
+	l.Title = strings.Repeat("я", 100)
+	created, err := b.CreateListing(ctx, &l)
+	require.NoError(t, err)
+
+	title := "ab"
+	_, err = b.UpdateListing(ctx, created.ID, storage.ListingUpdate{Title: &title})
+	assert.ErrorIs(t, err, storage.ErrInvalid)
+
+	stored, err := b.GetListingByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, l.Title, stored.Title, "a rejected update changes nothing")
+
+	// Prices are kept with cent precision.
+	price := 10.125
+	updated, err := b.UpdateListing(ctx, created.ID, storage.ListingUpdate{Price: &price})
+	require.NoError(t, err)
+	assert.InDelta(t, 10.13, updated.Price, 1e-9)
+}
+
+func testListSorting(t *testing.T, b Backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice")
+
+	cheap := createListing(t, b, user.ID, "Cheap item", "The cheapest of them all", 10)
+	tieA := createListing(t, b, user.ID, "Middle item A", "Priced like item B", 50)
+	tieB := createListing(t, b, user.ID, "Middle item B", "Priced like item A", 50)
+	pricey := createListing(t, b, user.ID, "Pricey item", "The most expensive one", 90)
+
+	tests := []struct {
+		sortBy, sortOrder string
+		want              []int64
+	}{
+		{storage.SortByPrice, storage.SortAsc, []int64{cheap.ID, tieA.ID, tieB.ID, pricey.ID}},
+		{storage.SortByPrice, storage.SortDesc, []int64{pricey.ID, tieB.ID, tieA.ID, cheap.ID}},
+		{storage.SortByCreatedAt, storage.SortAsc, []int64{cheap.ID, tieA.ID, tieB.ID, pricey.ID}},
+		{storage.SortByCreatedAt, storage.SortDesc, []int64{pricey.ID, tieB.ID, tieA.ID, cheap.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sortBy+"_"+tt.sortOrder, func(t *testing.T) {
+			got, err := b.ListListings(ctx, storage.ListFilter{Limit: 10, SortBy: tt.sortBy, SortOrder: tt.sortOrder})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids(got))
+		})
+	}
+}
+
+func testListKeysetPagination(t *testing.T, b Backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice")
+	for i, price := range []float64{30, 10, 20, 10, 30, 20, 10} {
+		createListing(t, b, user.ID, "Listing", "Listing number "+string(rune('A'+i)), price)
+	}
+
+	for _, sortBy := range []string{storage.SortByPrice, storage.SortByCreatedAt} {
+		for _, sortOrder := range []string{storage.SortAsc, storage.SortDesc} {
+			t.Run(sortBy+"_"+sortOrder, func(t *testing.T) {
+				filter := storage.ListFilter{Limit: 100, SortBy: sortBy, SortOrder: sortOrder}
+				all, err := b.ListListings(ctx, filter)
+				require.NoError(t, err)
+				require.Len(t, all, 7)
+
+				var walked []int64
+				filter.Limit = 3
+				for {
+					page, err := b.ListListings(ctx, filter)
+					require.NoError(t, err)
+					walked = append(walked, ids(page)...)
+					if len(page) < filter.Limit {
+						break
+					}
+					filter.After = storage.CursorAfter(filter, page[len(page)-1])
+				}
+				assert.Equal(t, ids(all), walked)
+			})
+		}
+	}
+}
+
+func testListOffsetPagination(t *testing.T, b Backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice")
+	var want []int64
+	for i := 0; i < 5; i++ {
+		want = append(want, createListing(t, b, user.ID, "Listing", "Listing for offsets", float64(i+1)).ID)
+	}
+
+	filter := storage.ListFilter{Limit: 2, Offset: 2, SortBy: storage.SortByPrice, SortOrder: storage.SortAsc}
+	page, total, err := b.ListListingsWithTotal(ctx, filter)
+	require.NoError(t, err)
+	assert.Equal(t, want[2:4], ids(page))
+	assert.Equal(t, 5, total)
+
+	filter.Offset = 10
+	page, total, err = b.ListListingsWithTotal(ctx, filter)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+	assert.Equal(t, 5, total)
+}
+
+func testListFilters(t *testing.T, b Backend) {
+	ctx := context.Background()
+	alice := createUser(t, b, "alice")
+	bob := createUser(t, b, "bob")
+
+	cheap := createListing(t, b, alice.ID, "Cheap lamp", "A lamp for the desk", 15)
+	mid := createListing(t, b, bob.ID, "Office chair", "Comfortable office chair", 120)
+	pricey := createListing(t, b, bob.ID, "Sofa", "Three-seat leather sofa", 900)
+
+	imageURL := "https://img.example.com/chair.png"
+	_, err := b.UpdateListing(ctx, mid.ID, storage.ListingUpdate{ImageURL: &imageURL})
+	require.NoError(t, err)
+
+	stored, err := b.GetListingByID(ctx, mid.ID)
+	require.NoError(t, err)
+
+	hasImage, noImage := true, false
+	tests := []struct {
+		name   string
+		filter storage.ListFilter
+		want   []int64
+	}{
+		{"price range", storage.ListFilter{PriceMin: ptr(15.0), PriceMax: ptr(120.0)}, []int64{cheap.ID, mid.ID}},
+		{"author id", storage.ListFilter{AuthorID: &bob.ID}, []int64{mid.ID, pricey.ID}},
+		{"author login", storage.ListFilter{AuthorLogin: "alice"}, []int64{cheap.ID}},
+		{"has image", storage.ListFilter{HasImage: &hasImage}, []int64{mid.ID}},
+		{"has no image", storage.ListFilter{HasImage: &noImage}, []int64{cheap.ID, pricey.ID}},
+		{"created after", storage.ListFilter{CreatedAfter: &stored.CreatedAt}, []int64{mid.ID, pricey.ID}},
+		{"created before", storage.ListFilter{CreatedBefore: &stored.CreatedAt}, []int64{cheap.ID}},
+		{"mine", storage.ListFilter{ViewerID: &alice.ID, OnlyMine: true}, []int64{cheap.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.Limit = 10
+			tt.filter.SortBy, tt.filter.SortOrder = storage.SortByPrice, storage.SortAsc
+
+			got, total, err := b.ListListingsWithTotal(ctx, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids(got))
+			assert.Equal(t, len(tt.want), total)
+		})
+	}
+}
+
+func testListOwnership(t *testing.T, b Backend) {
+	ctx := context.Background()
+	alice := createUser(t, b, "alice")
+	bob := createUser(t, b, "bob")
+	mine := createListing(t, b, alice.ID, "My listing", "Listing of the viewer", 1)
+	createListing(t, b, bob.ID, "Their listing", "Listing of someone else", 2)
+
+	got, err := b.ListListings(ctx, storage.ListFilter{Limit: 10, ViewerID: &alice.ID})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	for _, l := range got {
+		assert.Equal(t, l.ID == mine.ID, l.IsOwned, "listing %d", l.ID)
+		if l.ID == mine.ID {
+			assert.Equal(t, "alice", l.AuthorLogin)
+		} else {
+			assert.Equal(t, "bob", l.AuthorLogin)
+		}
+	}
+}
+
+func testListSearch(t *testing.T, b Backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice")
+
+	inTitle := createListing(t, b, user.ID, "Vintage bicycle", "Steel frame, new tires", 300)
+	inDescription := createListing(t, b, user.ID, "Garage sale", "Tools, a lamp and an old bicycle", 50)
+	createListing(t, b, user.ID, "Kitchen table", "Oak table with four chairs", 200)
+
+	got, total, err := b.ListListingsWithTotal(ctx, storage.ListFilter{Limit: 10, Query: "bicycle"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{inTitle.ID, inDescription.ID}, ids(got), "title matches rank higher")
+	assert.Equal(t, 2, total)
+	for _, l := range got {
+		assert.Positive(t, l.Relevance)
+		assert.Contains(t, l.Highlight, "<mark>")
+	}
+	assert.Greater(t, got[0].Relevance, got[1].Relevance)
+
+	got, err = b.ListListings(ctx, storage.ListFilter{Limit: 10, Query: "bicycle -steel"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{inDescription.ID}, ids(got))
+
+	// Relevance pages are stable too.
+	filter := storage.ListFilter{Limit: 1, Query: "bicycle"}
+	first, err := b.ListListings(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	filter.After = storage.CursorAfter(filter, first[0])
+	second, err := b.ListListings(ctx, filter)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{inDescription.ID}, ids(second))
+}
+
+func testTxCommit(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	var listingID int64
+	err := b.WithTx(ctx, func(tx storage.Repos) error {
+		user, err := tx.Users.CreateUser(ctx, "alice", "hash")
+		if err != nil {
+			return err
+		}
+		l, err := tx.Listings.CreateListing(ctx, &models.Listing{
+			Title: "Road bike", Description: "Barely used road bike", Price: 100, UserID: user.ID,
+		})
+		if err != nil {
+			return err
+		}
+		listingID = l.ID
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = b.GetUserByUsername(ctx, "alice")
+	assert.NoError(t, err)
+	_, err = b.GetListingByID(ctx, listingID)
+	assert.NoError(t, err)
+}
+
+func testTxRollback(t *testing.T, b Backend) {
+	ctx := context.Background()
+	existing := createUser(t, b, "bob")
+
+	errAbort := errors.New("abort")
+	err := b.WithTxOptions(ctx, storage.TxOptions{Isolation: storage.Serializable}, func(tx storage.Repos) error {
+		if _, err := tx.Users.CreateUser(ctx, "alice", "hash"); err != nil {
+			return err
+		}
+		if _, err := tx.Users.UpdateUserRole(ctx, existing.ID, models.RoleAdmin); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+
+	_, err = b.GetUserByUsername(ctx, "alice")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	stored, err := b.GetUserByID(ctx, existing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleUser, stored.Role)
+
+	// A failed statement aborts the whole unit of work as well.
+	err = b.WithTx(ctx, func(tx storage.Repos) error {
+		if _, err := tx.Users.CreateUser(ctx, "carol", "hash"); err != nil {
+			return err
+		}
+		_, err := tx.Users.CreateUser(ctx, "bob", "hash")
+		return err
+	})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+
+	_, err = b.GetUserByUsername(ctx, "carol")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+// RevocationBackend is what RunRevocation exercises: a revocation store
+// together with the users its token versions belong to.
+type RevocationBackend interface {
+	storage.UserRepository
+	storage.RevocationStore
+}
+
+// RunRevocation runs the suite of storage.RevocationStore. newBackend is
+// called once per test and must return a backend without users or
+// revocations.
+func RunRevocation(t *testing.T, newBackend func(t *testing.T) RevocationBackend) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, b RevocationBackend)
+	}{
+		{"RevokeToken", testRevokeToken},
+		{"RevokeTokenPurgesExpired", testRevokeTokenPurgesExpired},
+		{"TokenVersion", testTokenVersion},
+		{"TokenVersionUnknownUser", testTokenVersionUnknownUser},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newBackend(t))
+		})
+	}
+}
+
+func testRevokeToken(t *testing.T, b RevocationBackend) {
+	ctx := context.Background()
+
+	revoked, err := b.IsTokenRevoked(ctx, "jti")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, b.RevokeToken(ctx, "jti", time.Now().Add(time.Minute)))
+	// Revoking twice is harmless.
+	require.NoError(t, b.RevokeToken(ctx, "jti", time.Now().Add(time.Minute)))
+
+	revoked, err = b.IsTokenRevoked(ctx, "jti")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func testRevokeTokenPurgesExpired(t *testing.T, b RevocationBackend) {
+	ctx := context.Background()
+
+	require.NoError(t, b.RevokeToken(ctx, "old", time.Now().Add(-time.Minute)))
+	require.NoError(t, b.RevokeToken(ctx, "new", time.Now().Add(time.Minute)))
+
+	revoked, err := b.IsTokenRevoked(ctx, "old")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	revoked, err = b.IsTokenRevoked(ctx, "new")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func testTokenVersion(t *testing.T, b RevocationBackend) {
+	ctx := context.Background()
+	alice, err := b.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+	bob, err := b.CreateUser(ctx, "bob", "hash")
+	require.NoError(t, err)
+
+	version, err := b.GetTokenVersion(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	version, err = b.IncrementTokenVersion(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	version, err = b.GetTokenVersion(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	version, err = b.GetTokenVersion(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+}
+
+// The tokens of a user that no longer exists must not verify, so there is
+// no version to compare them with.
+func testTokenVersionUnknownUser(t *testing.T, b RevocationBackend) {
+	ctx := context.Background()
+
+	_, err := b.GetTokenVersion(ctx, 42)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = b.IncrementTokenVersion(ctx, 42)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+// --- helpers ---
+
+func createUser(t *testing.T, b Backend, username string) *models.User {
+	t.Helper()
+	u, err := b.CreateUser(context.Background(), username, "hash")
+	require.NoError(t, err)
+	return u
+}
+
+// createListing also makes sure that consecutive listings get distinct
+// creation times, so that sorting by created_at is deterministic.
+func createListing(t *testing.T, b Backend, userID int64, title, description string, price float64) *models.Listing {
+	t.Helper()
+	time.Sleep(time.Millisecond)
+	l, err := b.CreateListing(context.Background(), &models.Listing{
+		Title:       title,
+		Description: description,
+		Price:       price,
+		UserID:      userID,
+	})
+	require.NoError(t, err)
+	return l
+}
+
+func ids(listings []*models.ListingWithAuthor) []int64 {
+	out := make([]int64, 0, len(listings))
+	for _, l := range listings {
+		out = append(out, l.ID)
+	}
+	return out
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
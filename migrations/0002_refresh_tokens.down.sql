@@ -0,0 +1,3 @@
+DROP INDEX idx_refresh_tokens_family_id;
+
+DROP TABLE refresh_tokens;
\ No newline at end of file
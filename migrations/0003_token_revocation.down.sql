@@ -0,0 +1,5 @@
+DROP INDEX idx_revoked_tokens_expires_at;
+
+DROP TABLE revoked_tokens;
+
+ALTER TABLE users DROP COLUMN token_version;
\ No newline at end of file
@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE revoked_tokens (
+    jti TEXT PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- Expired entries are purged on every revocation
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
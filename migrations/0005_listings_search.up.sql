@@ -0,0 +1,11 @@
+-- Listings are written in a mix of Russian and English, so both dictionaries
+-- contribute lexemes. Titles weigh more than descriptions when ranking.
+ALTER TABLE listings
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', title), 'A') ||
+        setweight(to_tsvector('english', title), 'A') ||
+        setweight(to_tsvector('russian', description), 'B') ||
+        setweight(to_tsvector('english', description), 'B')
+    ) STORED;
+
+CREATE INDEX idx_listings_search_vector ON listings USING GIN (search_vector);
@@ -0,0 +1,6 @@
+-- created_at has no time zone, and CURRENT_TIMESTAMP would store the wall
+-- clock of the session's TimeZone. Store UTC regardless of the server
+-- settings, which is what the date filters compare against.
+ALTER TABLE users ALTER COLUMN created_at SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE listings ALTER COLUMN created_at SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE refresh_tokens ALTER COLUMN created_at SET DEFAULT (now() AT TIME ZONE 'UTC');
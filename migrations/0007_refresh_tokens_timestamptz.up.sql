@@ -0,0 +1,9 @@
+-- expires_at and revoked_at were compared with NOW(), an instant, while
+-- the columns held a wall clock without a zone, so tokens expired early or
+-- late whenever the app and the session disagreed on the time zone. Store
+-- instants; the existing values were written by the app in UTC.
+ALTER TABLE refresh_tokens
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE TIMESTAMPTZ USING revoked_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at SET DEFAULT now();
@@ -0,0 +1,2 @@
+ALTER TABLE revoked_tokens
+    ALTER COLUMN expires_at TYPE TIMESTAMP USING expires_at AT TIME ZONE 'UTC';
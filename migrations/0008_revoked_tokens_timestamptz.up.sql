@@ -0,0 +1,5 @@
+-- The purge on every revocation compares expires_at with NOW(); as a wall
+-- clock without a zone it could drop revocations of tokens that were still
+-- valid. Store instants; the existing values were written by the app in UTC.
+ALTER TABLE revoked_tokens
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at AT TIME ZONE 'UTC';
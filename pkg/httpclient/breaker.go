@@ -0,0 +1,157 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+// maxHosts caps how many hosts breakers track, since the URLs come from
+// users and may name any number of hosts.
+const maxHosts = 1024
+
+// breakers keeps a circuit per host that has failed. A success forgets the
+// host, and so does a closed circuit whose last failure is older than the
+// cooldown, but only once room is needed for another host. When there are
+// maxHosts hosts and none has expired, the one that failed longest ago goes,
+// closed circuits before open ones.
+type breakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+}
+
+type circuit struct {
+	failures    int
+	lastFailure time.Time
+	open        bool
+	openedAt    time.Time
+	// probing is set while the single request let through after the
+	// cooldown is in flight.
+	probing bool
+}
+
+func newBreakers(threshold int, cooldown time.Duration) *breakers {
+	return &breakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		hosts:     make(map[string]*circuit),
+	}
+}
+
+// allow reports whether a request to host may be sent.
+func (b *breakers) allow(host string) bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.hosts[host]
+	if c == nil || !c.open {
+		return true
+	}
+	if c.probing || time.Since(c.openedAt) < b.cooldown {
+		return false
+	}
+	c.probing = true
+	return true
+}
+
+// success closes the circuit of host.
+func (b *breakers) success(host string) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.hosts, host)
+}
+
+// failure counts a failed request to host and reports whether it opened the
+// circuit.
+func (b *breakers) failure(host string) bool {
+	if b.threshold <= 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	c := b.hosts[host]
+	if c == nil {
+		if len(b.hosts) >= maxHosts {
+			b.evict(now)
+		}
+		c = &circuit{}
+		b.hosts[host] = c
+	}
+	if !c.open && now.Sub(c.lastFailure) > b.cooldown {
+		// Failures that far apart do not mean the host is down.
+		c.failures = 0
+	}
+	c.lastFailure = now
+	if c.open {
+		if c.probing {
+			c.probing = false
+			c.openedAt = now
+		}
+		return false
+	}
+
+	c.failures++
+	if c.failures < b.threshold {
+		return false
+	}
+	c.open = true
+	c.openedAt = now
+	return true
+}
+
+// evict makes room for another host: it drops the closed circuits that have
+// expired or, if there are none, the circuit that failed longest ago.
+func (b *breakers) evict(now time.Time) {
+	var (
+		victim string
+		oldest *circuit
+	)
+	for host, c := range b.hosts {
+		if !c.open && now.Sub(c.lastFailure) > b.cooldown {
+			delete(b.hosts, host)
+			continue
+		}
+		if oldest == nil || evictBefore(c, oldest) {
+			victim, oldest = host, c
+		}
+	}
+	if len(b.hosts) >= maxHosts {
+		delete(b.hosts, victim)
+	}
+}
+
+// evictBefore reports whether a should be evicted before b.
+func evictBefore(a, b *circuit) bool {
+	if a.open != b.open {
+		return !a.open
+	}
+	return a.lastFailure.Before(b.lastFailure)
+}
+
+// release ends a request to host that neither succeeded nor failed, such as
+// one cancelled by the caller, so that another probe may be sent.
+func (b *breakers) release(host string) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c := b.hosts[host]; c != nil {
+		c.probing = false
+	}
+}
@@ -0,0 +1,233 @@
+// Package httpclient is the client for requests the app makes to other
+// hosts. Every request is traced, bounded by a timeout and by the caller's
+// context, retried a few times when that is safe, and refused outright while
+// the host keeps failing.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+// ErrCircuitOpen is returned without making a request while the host's
+// circuit is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+const (
+	DefaultTimeout      = 5 * time.Second
+	DefaultRetryBackoff = 100 * time.Millisecond
+)
+
+// Options tune the client. Zero MaxRetries disables retries and zero
+// BreakerThreshold disables the circuit breaker.
+type Options struct {
+	// Timeout bounds each attempt, headers and body included.
+	Timeout time.Duration
+	// HostTimeouts override Timeout for some hosts, keyed by host name
+	// without the port.
+	HostTimeouts map[string]time.Duration
+
+	// MaxRetries is how many times a failed idempotent request is repeated.
+	// The n-th retry waits around RetryBackoff * 2^(n-1).
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// After BreakerThreshold failed requests in a row to a host, requests to
+	// it fail with ErrCircuitOpen for BreakerCooldown. Then a single request
+	// is let through and its outcome closes or reopens the circuit. Failures
+	// more than BreakerCooldown apart are not counted as in a row.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// Transport defaults to a clone of http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+type Client struct {
+	http     *http.Client
+	opts     Options
+	breakers *breakers
+}
+
+func New(opts Options) *Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultRetryBackoff
+	}
+	if opts.Transport == nil {
+		opts.Transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	return &Client{
+		http:     &http.Client{Transport: otelhttp.NewTransport(opts.Transport)},
+		opts:     opts,
+		breakers: newBreakers(opts.BreakerThreshold, opts.BreakerCooldown),
+	}
+}
+
+// Get fetches url within ctx.
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Do sends req like http.Client.Do does. A response with a 5xx status counts
+// as a failure of the host but is still returned to the caller once the
+// retries are exhausted.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := req.URL.Hostname()
+	log := logger.FromContext(ctx).With("component", "httpclient", "host", host)
+
+	if !c.breakers.allow(host) {
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := 0; ; attempt++ {
+		resp, err = c.attempt(req, attempt)
+		if attempt >= c.opts.MaxRetries || !retryable(req, resp, err) {
+			break
+		}
+
+		wait := c.backoff(attempt)
+		log.Debug("retrying request",
+			slog.Int("attempt", attempt+1),
+			slog.Duration("wait", wait),
+			slog.Any("err", err),
+			slog.Int("status", status(resp)),
+		)
+		discard(resp)
+
+		select {
+		case <-ctx.Done():
+			resp, err = nil, ctx.Err()
+		case <-time.After(wait):
+			continue
+		}
+		break
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		// The caller gave up; that says nothing about the host.
+		c.breakers.release(host)
+	case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+		if c.breakers.failure(host) {
+			log.Warn("circuit opened", slog.Duration("cooldown", c.opts.BreakerCooldown))
+		}
+	default:
+		c.breakers.success(host)
+	}
+	return resp, err
+}
+
+// attempt sends one copy of req under the host's timeout. The timeout keeps
+// running while the body is read and is released when it is closed.
+func (c *Client) attempt(req *http.Request, n int) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), c.timeout(req.URL.Hostname()))
+
+	r := req.Clone(ctx)
+	if n > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		r.Body = body
+	}
+
+	resp, err := c.http.Do(r)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (c *Client) timeout(host string) time.Duration {
+	if d, ok := c.opts.HostTimeouts[host]; ok && d > 0 {
+		return d
+	}
+	return c.opts.Timeout
+}
+
+// backoff is the exponential delay before retry n+1, with jitter so that
+// clients failing together do not retry together.
+func (c *Client) backoff(n int) time.Duration {
+	d := c.opts.RetryBackoff << n
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryable reports whether req may be sent again after it got resp or err.
+// Only requests without side effects are repeated, and only after errors
+// that may be transient.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func status(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
+
+// discard drops a response that is not going to be returned, reading a
+// little of the body so that the connection can be reused.
+func discard(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	_, _ = io.CopyN(io.Discard, resp.Body, 4096)
+	_ = resp.Body.Close()
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,276 @@
+package httpclient_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/justcgh9/vk-internship-application/pkg/httpclient"
+)
+
+// server answers with the statuses in order, repeating the last one, and
+// counts the requests it gets.
+func server(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(hits.Add(1))
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestDo_RetriesTransientFailures(t *testing.T) {
+	srv, hits := server(t, http.StatusServiceUnavailable, http.StatusOK)
+	c := httpclient.New(httpclient.Options{MaxRetries: 2, RetryBackoff: time.Millisecond})
+
+	resp, err := c.Get(context.Background(), srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.EqualValues(t, 2, hits.Load())
+}
+
+func TestDo_RetriesAreBounded(t *testing.T) {
+	srv, hits := server(t, http.StatusBadGateway)
+	c := httpclient.New(httpclient.Options{MaxRetries: 2, RetryBackoff: time.Millisecond})
+
+	resp, err := c.Get(context.Background(), srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.EqualValues(t, 3, hits.Load())
+}
+
+func TestDo_NoRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		status int
+	}{
+		{"not idempotent", http.MethodPost, http.StatusServiceUnavailable},
+		{"client error", http.MethodGet, http.StatusNotFound},
+		{"not implemented", http.MethodGet, http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, hits := server(t, tt.status)
+			c := httpclient.New(httpclient.Options{MaxRetries: 2, RetryBackoff: time.Millisecond})
+
+			req, err := http.NewRequest(tt.method, srv.URL, strings.NewReader("{}"))
+			require.NoError(t, err)
+			resp, err := c.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.EqualValues(t, 1, hits.Load())
+		})
+	}
+}
+
+func TestDo_HostTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := httpclient.New(httpclient.Options{
+		Timeout:      time.Minute,
+		HostTimeouts: map[string]time.Duration{"127.0.0.1": 20 * time.Millisecond},
+	})
+
+	start := time.Now()
+	_, err := c.Get(context.Background(), srv.URL)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestDo_RespectsContext(t *testing.T) {
+	srv, hits := server(t, http.StatusServiceUnavailable)
+	c := httpclient.New(httpclient.Options{MaxRetries: 5, RetryBackoff: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Get(ctx, srv.URL)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.EqualValues(t, 1, hits.Load())
+}
+
+func TestDo_CircuitBreaker(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(srv.Close)
+
+	c := httpclient.New(httpclient.Options{BreakerThreshold: 2, BreakerCooldown: 50 * time.Millisecond})
+	get := func() (int, error) {
+		resp, err := c.Get(context.Background(), srv.URL)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	for range 2 {
+		code, err := get()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, code)
+	}
+
+	_, err := get()
+	require.ErrorIs(t, err, httpclient.ErrCircuitOpen)
+	assert.EqualValues(t, 2, hits.Load())
+
+	// The probe after the cooldown fails and reopens the circuit.
+	time.Sleep(60 * time.Millisecond)
+	_, err = get()
+	require.NoError(t, err)
+	_, err = get()
+	require.ErrorIs(t, err, httpclient.ErrCircuitOpen)
+
+	// A successful probe closes it.
+	status.Store(http.StatusOK)
+	time.Sleep(60 * time.Millisecond)
+	for range 3 {
+		code, err := get()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+	}
+	assert.EqualValues(t, 6, hits.Load())
+}
+
+func TestDo_CircuitIsPerHost(t *testing.T) {
+	failing, _ := server(t, http.StatusInternalServerError)
+	healthy, _ := server(t, http.StatusOK)
+	c := httpclient.New(httpclient.Options{BreakerThreshold: 1, BreakerCooldown: time.Minute})
+
+	resp, err := c.Get(context.Background(), failing.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	_, err = c.Get(context.Background(), failing.URL)
+	require.ErrorIs(t, err, httpclient.ErrCircuitOpen)
+
+	// Both servers listen on 127.0.0.1; name the healthy one differently.
+	resp, err = c.Get(context.Background(), strings.Replace(healthy.URL, "127.0.0.1", "localhost", 1))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestDo_CircuitForgetsOldFailures(t *testing.T) {
+	srv, hits := server(t, http.StatusInternalServerError)
+	c := httpclient.New(httpclient.Options{BreakerThreshold: 2, BreakerCooldown: 20 * time.Millisecond})
+
+	for range 3 {
+		resp, err := c.Get(context.Background(), srv.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		time.Sleep(30 * time.Millisecond)
+	}
+	assert.EqualValues(t, 3, hits.Load())
+}
+
+// statusTransport answers every request with status without a network.
+type statusTransport int
+
+func (s statusTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: int(s), Body: http.NoBody, Request: r}, nil
+}
+
+// Many hosts that fail once must not push out the circuit of a host that is
+// down.
+func TestDo_OpenCircuitOutlivesManyHosts(t *testing.T) {
+	c := httpclient.New(httpclient.Options{
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+		Transport:        statusTransport(http.StatusInternalServerError),
+	})
+	get := func(host string) error {
+		resp, err := c.Get(context.Background(), "http://"+host+"/image.png")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	require.NoError(t, get("down.example"))
+	require.NoError(t, get("down.example"))
+	for i := range 5000 {
+		require.NoError(t, get(fmt.Sprintf("host-%d.example", i)))
+	}
+	assert.ErrorIs(t, get("down.example"), httpclient.ErrCircuitOpen)
+}
+
+func TestDo_PropagatesTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	var traceparent atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent.Store(r.Header.Get("traceparent"))
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "handler")
+	resp, err := httpclient.New(httpclient.Options{}).Get(ctx, srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	span.End()
+
+	assert.Contains(t, traceparent.Load(), span.SpanContext().TraceID().String())
+	require.Len(t, recorder.Ended(), 2)
+	assert.Equal(t, span.SpanContext().TraceID(), recorder.Ended()[0].SpanContext().TraceID())
+}
+
+func TestDo_BodyOutlivesDo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("late body"))
+	}))
+	t.Cleanup(srv.Close)
+
+	resp, err := httpclient.New(httpclient.Options{Timeout: time.Second}).Get(context.Background(), srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "late body", string(body))
+}
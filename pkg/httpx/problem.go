@@ -0,0 +1,106 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypePrefix namespaces the type URI of every problem; the suffix is
+// the problem code.
+const ProblemTypePrefix = "urn:vk-intern-app:problem:"
+
+// Problem is an RFC 7807 problem details document. Code is the stable,
+// machine-readable identifier clients should switch on.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	TraceID  string       `json:"trace_id,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Error is an error with a known HTTP representation. Err is the underlying
+// cause; it is kept for logs and errors.Is and never shown to clients.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+func NewError(status int, code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithFields returns a copy of e listing the offending input fields.
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	c := *e
+	c.Fields = append(append([]FieldError(nil), e.Fields...), fields...)
+	return &c
+}
+
+// WithDetail returns a copy of e with a more specific detail message.
+func (e *Error) WithDetail(detail string) *Error {
+	c := *e
+	c.Detail = detail
+	return &c
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// Errors shared by every handler.
+var (
+	ErrInvalidJSON      = NewError(http.StatusUnprocessableEntity, "invalid_json", "request body is not valid JSON")
+	ErrValidation       = NewError(http.StatusUnprocessableEntity, "validation_failed", "request body failed validation")
+	ErrInvalidQuery     = NewError(http.StatusBadRequest, "invalid_query", "query parameters failed validation")
+	ErrUnauthorized     = NewError(http.StatusUnauthorized, "unauthorized", "authentication required")
+	ErrForbidden        = NewError(http.StatusForbidden, "forbidden", "not allowed to perform this action")
+	ErrNotFound         = NewError(http.StatusNotFound, "not_found", "resource not found")
+	ErrConflict         = NewError(http.StatusConflict, "conflict", "resource already exists")
+	ErrMethodNotAllowed = NewError(http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed for this resource")
+	ErrInternal         = NewError(http.StatusInternalServerError, "internal_error", "internal server error")
+)
+
+// WriteProblem renders e as application/problem+json for request r.
+func WriteProblem(w http.ResponseWriter, r *http.Request, e *Error) {
+	p := Problem{
+		Type:     ProblemTypePrefix + e.Code,
+		Title:    http.StatusText(e.Status),
+		Status:   e.Status,
+		Detail:   e.Detail,
+		Instance: r.URL.Path,
+		Code:     e.Code,
+		Errors:   e.Fields,
+	}
+	if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+		p.TraceID = sc.TraceID().String()
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(e.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
@@ -2,30 +2,134 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"sync"
+	"time"
 
 	"go.opentelemetry.io/otel/trace"
 )
 
 var (
 	Log *slog.Logger
+
+	// Level is the minimum level of Log. It can be changed while the app is
+	// running.
+	Level = new(slog.LevelVar)
 )
 
+// Options configure Log; the zero value logs JSON at info level to stdout.
+type Options struct {
+	Level  slog.Level
+	Format string    // "json" or "text"
+	Output io.Writer // os.Stdout when nil
+	// Redact lists attribute keys whose values are masked, compared
+	// case-insensitively and at any depth of groups.
+	Redact   []string
+	Sampling Sampling
+}
+
+// Sampling limits how many records with the same level and message are
+// written per Tick: the first Initial ones, then every Thereafter-th. Warnings
+// and errors are never dropped. A zero Initial disables sampling.
+type Sampling struct {
+	Initial    int
+	Thereafter int
+	Tick       time.Duration
+}
+
 func Init(level slog.Level) {
-	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: level,
-	})
+	_ = Setup(Options{Level: level})
+}
+
+// Setup replaces Log according to opts.
+func Setup(opts Options) error {
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	Level.Set(opts.Level)
+
+	handlerOpts := &slog.HandlerOptions{Level: Level}
+	var handler slog.Handler
+	switch opts.Format {
+	case "", "json":
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	case "text":
+		handler = slog.NewTextHandler(out, handlerOpts)
+	default:
+		return fmt.Errorf("unknown log format %q", opts.Format)
+	}
+
+	if len(opts.Redact) > 0 {
+		handler = NewRedactHandler(handler, opts.Redact...)
+	}
+	if opts.Sampling.Initial > 0 {
+		handler = NewSampleHandler(handler, opts.Sampling)
+	}
+
 	Log = slog.New(handler)
+	return nil
+}
+
+type scopeKey struct{}
+
+// scope holds the logger of one request. It is shared by pointer, so that
+// attributes learned deep in the handler chain, such as the user, also reach
+// the access log written by the outermost middleware.
+type scope struct {
+	mu  sync.Mutex
+	log *slog.Logger
+	// traceID is the trace the logger already carries, if any.
+	traceID trace.TraceID
+}
+
+// NewContext starts a request scope: the logger FromContext returns for ctx
+// carries args and the ID of the trace in ctx. It is built once here, so
+// values are resolved now; pass an slog.LogValuer for something only known
+// later in the request to the log call itself instead.
+func NewContext(ctx context.Context, args ...any) context.Context {
+	if Log == nil {
+		Init(slog.LevelInfo)
+	}
+
+	s := &scope{log: Log.With(args...)}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		s.traceID = sc.TraceID()
+		s.log = s.log.With("trace_id", s.traceID.String())
+	}
+	return context.WithValue(ctx, scopeKey{}, s)
+}
+
+// With adds attributes to the logger of the request scope in ctx. Outside a
+// request scope it does nothing.
+func With(ctx context.Context, args ...any) {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	s.log = s.log.With(args...)
+	s.mu.Unlock()
 }
 
 func FromContext(ctx context.Context) *slog.Logger {
 	if Log == nil {
 		Init(slog.LevelInfo)
 	}
-	span := trace.SpanFromContext(ctx)
-	if span == nil || !span.SpanContext().HasTraceID() {
-		return Log
+
+	log, traceID := Log, trace.TraceID{}
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		s.mu.Lock()
+		log, traceID = s.log, s.traceID
+		s.mu.Unlock()
+	}
+
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() || sc.TraceID() == traceID {
+		return log
 	}
-	return Log.With("trace_id", span.SpanContext().TraceID().String())
+	return log.With("trace_id", sc.TraceID().String())
 }
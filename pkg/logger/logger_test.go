@@ -0,0 +1,156 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justcgh9/vk-internship-application/pkg/logger"
+)
+
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+type credentials struct{ user, password string }
+
+func (c credentials) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("Username", c.user), slog.String("password", c.password))
+}
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(logger.NewRedactHandler(slog.NewJSONHandler(&buf, nil), "password", "Authorization", "username"))
+
+	log.With("authorization", "Bearer abc").Info("login",
+		"username", "alice",
+		"status", 401,
+		slog.Group("request", slog.String("Password", "hunter2"), slog.String("path", "/auth/login")),
+		"creds", credentials{"bob", "secret"},
+	)
+
+	lines := decode(t, &buf)
+	require.Len(t, lines, 1)
+	line := lines[0]
+	assert.Equal(t, "[REDACTED]", line["authorization"])
+	assert.Equal(t, "[REDACTED]", line["username"])
+	assert.EqualValues(t, 401, line["status"])
+	assert.Equal(t, map[string]any{"Password": "[REDACTED]", "path": "/auth/login"}, line["request"])
+	assert.Equal(t, map[string]any{"Username": "[REDACTED]", "password": "[REDACTED]"}, line["creds"])
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestSampleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(logger.NewSampleHandler(slog.NewJSONHandler(&buf, nil), logger.Sampling{
+		Initial:    2,
+		Thereafter: 3,
+		Tick:       time.Hour,
+	}))
+
+	for i := range 8 {
+		log.With("i", i).Info("repeated")
+	}
+	log.Info("other")
+	log.Error("failure")
+	log.Error("failure")
+	log.Error("failure")
+
+	var repeated []float64
+	counts := map[string]int{}
+	for _, line := range decode(t, &buf) {
+		counts[line["msg"].(string)]++
+		if line["msg"] == "repeated" {
+			repeated = append(repeated, line["i"].(float64))
+		}
+	}
+	// First two, then every third: the 5th and the 8th.
+	assert.Equal(t, []float64{0, 1, 4, 7}, repeated)
+	assert.Equal(t, 1, counts["other"])
+	assert.Equal(t, 3, counts["failure"], "errors are never sampled")
+}
+
+func TestSetup(t *testing.T) {
+	prev, prevLevel := logger.Log, logger.Level.Level()
+	t.Cleanup(func() {
+		logger.Log = prev
+		logger.Level.Set(prevLevel)
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, logger.Setup(logger.Options{
+		Level:  slog.LevelWarn,
+		Format: "text",
+		Output: &buf,
+		Redact: []string{"token"},
+	}))
+
+	logger.Log.Info("hidden")
+	logger.Log.Warn("shown", "token", "abc")
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "token=[REDACTED]")
+
+	// The level can be changed while the app runs.
+	logger.Level.Set(slog.LevelDebug)
+	logger.Log.Debug("now shown")
+	assert.Contains(t, buf.String(), "now shown")
+
+	assert.Error(t, logger.Setup(logger.Options{Format: "xml"}))
+}
+
+// countingHandler counts how often a logger is derived from it.
+type countingHandler struct {
+	slog.Handler
+	withAttrs *int
+}
+
+func (h countingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	*h.withAttrs++
+	return countingHandler{h.Handler.WithAttrs(attrs), h.withAttrs}
+}
+
+func TestFromContext(t *testing.T) {
+	prev := logger.Log
+	t.Cleanup(func() { logger.Log = prev })
+
+	var buf bytes.Buffer
+	withAttrs := 0
+	logger.Log = slog.New(countingHandler{slog.NewJSONHandler(&buf, nil), &withAttrs})
+
+	ctx := logger.NewContext(context.Background(), "request_id", "abc")
+	logger.FromContext(ctx).Info("first")
+	logger.With(ctx, "user_id", 7)
+	logger.FromContext(ctx).Info("second")
+	logger.FromContext(ctx).Info("third")
+
+	// One logger for the scope and one for the attribute added later; taking
+	// the logger does not rebuild it.
+	assert.Equal(t, 2, withAttrs)
+
+	lines := decode(t, &buf)
+	require.Len(t, lines, 3)
+	for _, line := range lines {
+		assert.Equal(t, "abc", line["request_id"])
+	}
+	assert.NotContains(t, lines[0], "user_id")
+	assert.EqualValues(t, 7, lines[2]["user_id"])
+}
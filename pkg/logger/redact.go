@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// RedactHandler masks the values of sensitive attributes before passing
+// records on.
+type RedactHandler struct {
+	next slog.Handler
+	keys map[string]struct{}
+}
+
+func NewRedactHandler(next slog.Handler, keys ...string) *RedactHandler {
+	h := &RedactHandler{next: next, keys: make(map[string]struct{}, len(keys))}
+	for _, k := range keys {
+		h.keys[strings.ToLower(k)] = struct{}{}
+	}
+	return h
+}
+
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		masked[i] = h.redact(a)
+	}
+	return &RedactHandler{next: h.next.WithAttrs(masked), keys: h.keys}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{next: h.next.WithGroup(name), keys: h.keys}
+}
+
+func (h *RedactHandler) redact(a slog.Attr) slog.Attr {
+	if _, ok := h.keys[strings.ToLower(a.Key)]; ok {
+		return slog.String(a.Key, redacted)
+	}
+
+	// Resolve first: a LogValuer may turn into a group with secrets inside.
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		return a
+	}
+	group := a.Value.Group()
+	masked := make([]slog.Attr, len(group))
+	for i, ga := range group {
+		masked[i] = h.redact(ga)
+	}
+	return slog.Attr{Key: a.Key, Value: slog.GroupValue(masked...)}
+}
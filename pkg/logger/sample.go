@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SampleHandler drops repeated debug and info records, see Sampling.
+type SampleHandler struct {
+	next    slog.Handler
+	sampler *sampler
+}
+
+func NewSampleHandler(next slog.Handler, cfg Sampling) *SampleHandler {
+	if cfg.Tick <= 0 {
+		cfg.Tick = time.Second
+	}
+	return &SampleHandler{
+		next:    next,
+		sampler: &sampler{cfg: cfg, counts: make(map[sampleKey]int)},
+	}
+}
+
+func (h *SampleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn && !h.sampler.allow(r.Level, r.Message, r.Time) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// Loggers derived with With share the counters of their parent, so that a
+// message counts the same however many request loggers write it.
+func (h *SampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SampleHandler{next: h.next.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h *SampleHandler) WithGroup(name string) slog.Handler {
+	return &SampleHandler{next: h.next.WithGroup(name), sampler: h.sampler}
+}
+
+type sampleKey struct {
+	level   slog.Level
+	message string
+}
+
+type sampler struct {
+	cfg Sampling
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[sampleKey]int
+}
+
+func (s *sampler) allow(level slog.Level, message string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.start) >= s.cfg.Tick {
+		s.start = now
+		clear(s.counts)
+	}
+
+	key := sampleKey{level, message}
+	s.counts[key]++
+	n := s.counts[key] - s.cfg.Initial
+	return n <= 0 || s.cfg.Thereafter > 0 && n%s.cfg.Thereafter == 0
+}
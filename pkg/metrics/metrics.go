@@ -2,55 +2,135 @@ package metrics
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// unmatchedRoute labels requests no route matched, so that scanners probing
+// random paths add a single series rather than one per path.
+const unmatchedRoute = "unmatched"
+
+// Options tune the histograms; nil buckets fall back to the defaults.
+type Options struct {
+	DurationBuckets []float64
+	SizeBuckets     []float64
+}
+
 var (
-	requestsTotal = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "http_requests_total",
-			Help: "Total HTTP requests",
-		},
-		[]string{"method", "path", "status"},
-	)
+	DefaultDurationBuckets = prometheus.DefBuckets
+	// DefaultSizeBuckets span 100 B to 10 MB.
+	DefaultSizeBuckets = prometheus.ExponentialBuckets(100, 10, 6)
+)
+
+// Metrics owns a registry of its own instead of the global default one, so
+// that every instance, one per test included, starts from zero.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requestsTotal   *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	responseSize    *prometheus.HistogramVec
+	inFlight        prometheus.Gauge
+}
 
-	requestDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
-			Help:    "Duration of HTTP requests",
-			Buckets: prometheus.DefBuckets,
-		},
-		[]string{"method", "path"},
+func New(opts Options) *Metrics {
+	if opts.DurationBuckets == nil {
+		opts.DurationBuckets = DefaultDurationBuckets
+	}
+	if opts.SizeBuckets == nil {
+		opts.SizeBuckets = DefaultSizeBuckets
+	}
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_requests_total",
+				Help: "Total HTTP requests",
+			},
+			[]string{"method", "route", "status"},
+		),
+		requestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_request_duration_seconds",
+				Help:    "Duration of HTTP requests",
+				Buckets: opts.DurationBuckets,
+			},
+			[]string{"method", "route"},
+		),
+		responseSize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_response_size_bytes",
+				Help:    "Size of HTTP response bodies",
+				Buckets: opts.SizeBuckets,
+			},
+			[]string{"method", "route"},
+		),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests being served",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.requestsTotal,
+		m.requestDuration,
+		m.responseSize,
+		m.inFlight,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 	)
-)
+	return m
+}
 
-func Init() {
-	prometheus.MustRegister(requestsTotal, requestDuration)
+// Registry is where further collectors of the app are registered.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
 }
 
-func Middleware(next http.Handler) http.Handler {
+// Handler serves the metrics of the registry.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware records every request, labelled with the chi route pattern such
+// as /listings/{id} rather than the path, which would make a series per id.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
 
-		ww := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
 		next.ServeHTTP(ww, r)
 
-		duration := time.Since(start).Seconds()
-		path := r.URL.Path // (можно заменить на роутер-путь, если нужно)
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK // nothing was written
+		}
+		route := routePattern(r)
 
-		requestsTotal.WithLabelValues(r.Method, path, http.StatusText(ww.status)).Inc()
-		requestDuration.WithLabelValues(r.Method, path).Observe(duration)
+		m.requestsTotal.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		m.requestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		m.responseSize.WithLabelValues(r.Method, route).Observe(float64(ww.BytesWritten()))
 	})
 }
 
-type statusResponseWriter struct {
-	http.ResponseWriter
-	status int
-}
-
-func (w *statusResponseWriter) WriteHeader(code int) {
-	w.status = code
-	w.ResponseWriter.WriteHeader(code)
+// routePattern is complete only once the request has been routed, so it is
+// read after the handler returns.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return unmatchedRoute
+	}
+	if pattern := rctx.RoutePattern(); pattern != "" {
+		return pattern
+	}
+	return unmatchedRoute
 }
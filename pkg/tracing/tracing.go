@@ -2,28 +2,81 @@ package tracing
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"os"
+	"sync"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/credentials"
 )
 
-func Init(ctx context.Context, serviceName string) (func(context.Context) error, error) {
-	exporter, err := otlptracehttp.New(ctx,
-		otlptracehttp.WithEndpoint("jaeger:4318"),
-		otlptracehttp.WithInsecure(),
-	)
+// Exporters Init knows.
+const (
+	ExporterOTLPHTTP = "otlp-http"
+	ExporterOTLPGRPC = "otlp-grpc"
+	ExporterStdout   = "stdout"
+	ExporterNone     = "none"
+)
+
+type Options struct {
+	ServiceName string
+	Exporter    string
+	// Endpoint is the host:port of the collector. When empty, the OTLP
+	// exporters fall back to OTEL_EXPORTER_OTLP_ENDPOINT and then localhost.
+	Endpoint string
+	// TLS secures the connection to the collector, verified against the
+	// system roots or, when CAFile is set, the certificates in it.
+	TLS    bool
+	CAFile string
+	// SampleRatio is the share of new traces recorded. Requests that arrive
+	// with a sampled parent are always recorded, so traces stay whole
+	// across services.
+	SampleRatio float64
+	// Output receives the spans of the stdout exporter, os.Stderr when nil
+	// so that they do not mix with the JSON logs on stdout.
+	Output io.Writer
+}
+
+var exporter *healthExporter
+
+// Init sets up the global tracer provider and the W3C trace context and
+// baggage propagators. With ExporterNone spans are not recorded at all, which
+// is what tests want. The returned function flushes and stops the exporter.
+func Init(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	exporter = nil
+
+	if opts.Exporter == ExporterNone {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return func(context.Context) error { return nil }, nil
+	}
+
+	spanExporter, err := newExporter(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
+	exporter = &healthExporter{SpanExporter: spanExporter}
 
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
 		sdktrace.WithResource(resource.NewWithAttributes(
 			semconv.SchemaURL,
-			semconv.ServiceName(serviceName),
+			semconv.ServiceName(opts.ServiceName),
 		)),
 	)
 
@@ -31,3 +84,102 @@ func Init(ctx context.Context, serviceName string) (func(context.Context) error,
 
 	return tp.Shutdown, nil
 }
+
+func newExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, error) {
+	var tlsConfig *tls.Config
+	if opts.TLS {
+		var err error
+		if tlsConfig, err = newTLSConfig(opts.CAFile); err != nil {
+			return nil, err
+		}
+	}
+
+	switch opts.Exporter {
+	case ExporterOTLPHTTP:
+		var httpOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			httpOpts = append(httpOpts, otlptracehttp.WithEndpoint(opts.Endpoint))
+		}
+		if tlsConfig != nil {
+			httpOpts = append(httpOpts, otlptracehttp.WithTLSClientConfig(tlsConfig))
+		} else {
+			httpOpts = append(httpOpts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, httpOpts...)
+
+	case ExporterOTLPGRPC:
+		var grpcOpts []otlptracegrpc.Option
+		if opts.Endpoint != "" {
+			grpcOpts = append(grpcOpts, otlptracegrpc.WithEndpoint(opts.Endpoint))
+		}
+		if tlsConfig != nil {
+			grpcOpts = append(grpcOpts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+		} else {
+			grpcOpts = append(grpcOpts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, grpcOpts...)
+
+	case ExporterStdout:
+		out := opts.Output
+		if out == nil {
+			out = os.Stderr
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(out))
+
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+}
+
+func newTLSConfig(caFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile == "" {
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read collector CA: %w", err)
+	}
+	cfg.RootCAs = x509.NewCertPool()
+	if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in %s", caFile)
+	}
+	return cfg, nil
+}
+
+// Check returns the error of the last span export if it failed. Nothing has
+// failed before Init, before the first batch is sent, or when nothing is
+// exported.
+func Check(context.Context) error {
+	if exporter == nil {
+		return nil
+	}
+	return exporter.err()
+}
+
+// healthExporter remembers whether the last export went through.
+type healthExporter struct {
+	sdktrace.SpanExporter
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+func (e *healthExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+
+	e.mu.Lock()
+	e.lastErr = err
+	e.mu.Unlock()
+	return err
+}
+
+func (e *healthExporter) err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lastErr != nil {
+		return fmt.Errorf("export spans: %w", e.lastErr)
+	}
+	return nil
+}
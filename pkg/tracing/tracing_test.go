@@ -0,0 +1,123 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/justcgh9/vk-internship-application/pkg/tracing"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestInit_None(t *testing.T) {
+	shutdown, err := tracing.Init(context.Background(), tracing.Options{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+
+	_, span := otel.Tracer("test").Start(context.Background(), "span")
+	defer span.End()
+	assert.False(t, span.IsRecording())
+	assert.NoError(t, tracing.Check(context.Background()))
+}
+
+func TestInit_Propagators(t *testing.T) {
+	shutdown, err := tracing.Init(context.Background(), tracing.Options{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+
+	header := http.Header{}
+	header.Set("traceparent", traceparent)
+	header.Set("baggage", "tenant=acme")
+
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+
+	sc := trace.SpanContextFromContext(ctx)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+	assert.True(t, sc.IsRemote())
+	assert.Equal(t, "acme", baggage.FromContext(ctx).Member("tenant").Value())
+}
+
+func TestInit_ParentBasedSampler(t *testing.T) {
+	shutdown, err := tracing.Init(context.Background(), tracing.Options{
+		ServiceName: "test",
+		Exporter:    tracing.ExporterStdout,
+		SampleRatio: 0,
+	})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+
+	_, root := otel.Tracer("test").Start(context.Background(), "root")
+	root.End()
+	assert.False(t, root.SpanContext().IsSampled(), "new traces follow the ratio")
+
+	header := http.Header{}
+	header.Set("traceparent", traceparent)
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+	_, child := otel.Tracer("test").Start(ctx, "child")
+	child.End()
+	assert.True(t, child.SpanContext().IsSampled(), "a sampled parent is always followed")
+}
+
+func TestInit_UnknownExporter(t *testing.T) {
+	_, err := tracing.Init(context.Background(), tracing.Options{Exporter: "zipkin"})
+	assert.Error(t, err)
+}
+
+func TestInit_MissingCAFile(t *testing.T) {
+	_, err := tracing.Init(context.Background(), tracing.Options{
+		Exporter: tracing.ExporterOTLPGRPC,
+		TLS:      true,
+		CAFile:   "/nonexistent/ca.pem",
+	})
+	assert.Error(t, err)
+}
+
+func TestInit_StdoutOutput(t *testing.T) {
+	var buf bytes.Buffer
+	shutdown, err := tracing.Init(context.Background(), tracing.Options{
+		ServiceName: "test",
+		Exporter:    tracing.ExporterStdout,
+		SampleRatio: 1,
+		Output:      &buf,
+	})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "written-span")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	assert.Contains(t, buf.String(), "written-span")
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestCheck_ResetByInit(t *testing.T) {
+	shutdown, err := tracing.Init(context.Background(), tracing.Options{
+		Exporter:    tracing.ExporterStdout,
+		SampleRatio: 1,
+		Output:      failingWriter{},
+	})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "span")
+	span.End()
+	_ = shutdown(context.Background())
+	require.Error(t, tracing.Check(context.Background()))
+
+	shutdown, err = tracing.Init(context.Background(), tracing.Options{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+	assert.NoError(t, tracing.Check(context.Background()))
+}